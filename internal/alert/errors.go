@@ -0,0 +1,8 @@
+package alert
+
+import "errors"
+
+var (
+	ErrInvalidRuleError   = errors.New("invalid alert rule")
+	ErrDuplicateRuleError = errors.New("duplicate alert rule")
+)
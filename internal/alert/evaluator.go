@@ -0,0 +1,298 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
+	"github.com/kirill-shtrykov/minimon/internal/db/generated"
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+type State string
+
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Transition is a change of the alert state of a key.
+// Since is when the previous state was entered.
+type Transition struct {
+	Rule  string
+	Key   string
+	Value float64
+	From  State
+	To    State
+	Since time.Time
+	At    time.Time
+}
+
+type stateKey struct {
+	rule string
+	key  string
+}
+
+type status struct {
+	state State
+	value float64
+	since time.Time
+}
+
+// Evaluator tracks the alert state of every rule and matched key.
+// States other than inactive are persisted, so a restart picks up
+// pending and firing alerts instead of raising them again.
+// Stale series and series no longer collected are evaluated as
+// not breaking the threshold.
+type Evaluator struct {
+	repo    *db.Repo
+	rules   []Rule
+	states  map[stateKey]status
+	started time.Time
+}
+
+// next returns the state of a key after evaluating the rule.
+func (r Rule) next(cur status, holds bool, now time.Time) State {
+	switch cur.state {
+	case StatePending:
+		if !holds {
+			return StateInactive
+		}
+
+		if now.Sub(cur.since) >= r.For {
+			return StateFiring
+		}
+	case StateFiring:
+		if !holds {
+			return StateResolved
+		}
+	default:
+		if !holds {
+			return StateInactive
+		}
+
+		if r.For <= 0 {
+			return StateFiring
+		}
+
+		return StatePending
+	}
+
+	return cur.state
+}
+
+// Evaluate checks the rules against the latest values and returns
+// the state transitions. States are changed by Save.
+func (e *Evaluator) Evaluate(latest []monitor.Latest, now time.Time) []Transition {
+	var transitions []Transition
+
+	current := make(map[stateKey]struct{})
+
+	for _, rule := range e.rules {
+		for _, l := range latest {
+			if l.Stale(now) || !rule.Matches(l.Key(), l.Labels) {
+				continue
+			}
+
+			v, ok := l.Float()
+			if !ok {
+				continue
+			}
+
+			sk := stateKey{rule: rule.Name, key: l.Series()}
+			current[sk] = struct{}{}
+
+			if t, ok := e.step(rule, sk, v, rule.Holds(v), now); ok {
+				transitions = append(transitions, t)
+			}
+		}
+	}
+
+	// series restored on start are given time to be collected
+	if len(latest) == 0 || now.Sub(e.started) <= grace(latest) {
+		return transitions
+	}
+
+	for _, sk := range e.expired(current) {
+		if t, ok := e.step(e.rule(sk.rule), sk, e.states[sk].value, false, now); ok {
+			transitions = append(transitions, t)
+		}
+	}
+
+	return transitions
+}
+
+// grace returns the longest time a latest value is current.
+func grace(latest []monitor.Latest) time.Duration {
+	var d time.Duration
+
+	for _, l := range latest {
+		d = max(d, l.StaleAfter())
+	}
+
+	return d
+}
+
+// step returns the transition of the key, false if
+// the state is unchanged.
+func (e *Evaluator) step(rule Rule, sk stateKey, v float64, holds bool, now time.Time) (Transition, bool) {
+	cur, ok := e.states[sk]
+	if !ok {
+		cur = status{state: StateInactive}
+	}
+
+	next := rule.next(cur, holds, now)
+	if next == cur.state {
+		return Transition{}, false
+	}
+
+	return Transition{
+		Rule:  rule.Name,
+		Key:   sk.key,
+		Value: v,
+		From:  cur.state,
+		To:    next,
+		Since: cur.since,
+		At:    now,
+	}, true
+}
+
+// expired returns the keys with a state and no current value
+// ordered by rule and key.
+func (e *Evaluator) expired(current map[stateKey]struct{}) []stateKey {
+	var keys []stateKey
+
+	for sk := range e.states {
+		if _, ok := current[sk]; !ok {
+			keys = append(keys, sk)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].rule != keys[j].rule {
+			return keys[i].rule < keys[j].rule
+		}
+
+		return keys[i].key < keys[j].key
+	})
+
+	return keys
+}
+
+func (e *Evaluator) rule(name string) Rule {
+	for _, r := range e.rules {
+		if r.Name == name {
+			return r
+		}
+	}
+
+	return Rule{Name: name}
+}
+
+// Save persists the states entered by the transitions and runs queue
+// in the same transaction, so notifications are queued if and only
+// if the states change. States are kept unchanged on failure and the
+// transitions are returned again by the next evaluation.
+func (e *Evaluator) Save(ctx context.Context, transitions []Transition, queue func(tx *db.Repo) error) error {
+	err := e.repo.Tx(ctx, func(tx *db.Repo) error {
+		for _, t := range transitions {
+			if err := save(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+
+		return queue(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save alert states: %w", err)
+	}
+
+	for _, t := range transitions {
+		sk := stateKey{rule: t.Rule, key: t.Key}
+
+		if t.To == StateInactive {
+			delete(e.states, sk)
+		} else {
+			e.states[sk] = status{state: t.To, value: t.Value, since: t.At}
+		}
+	}
+
+	return nil
+}
+
+func save(ctx context.Context, tx *db.Repo, t Transition) error {
+	if t.To == StateInactive {
+		return tx.DeleteAlertState(ctx, t.Rule, t.Key)
+	}
+
+	return tx.SetAlertState(ctx, generated.UpsertAlertStateParams{
+		Rule:  t.Rule,
+		Key:   t.Key,
+		State: string(t.To),
+		Value: t.Value,
+		Since: t.At,
+	})
+}
+
+// load restores persisted states, states of rules no longer
+// configured are dropped.
+func (e *Evaluator) load(ctx context.Context) error {
+	states, err := e.repo.AlertStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load alert states: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(e.rules))
+	for _, r := range e.rules {
+		names[r.Name] = struct{}{}
+	}
+
+	for _, s := range states {
+		if _, ok := names[s.Rule]; !ok {
+			if err := e.repo.DeleteAlertState(ctx, s.Rule, s.Key); err != nil {
+				return fmt.Errorf("failed to drop alert state: %w", err)
+			}
+
+			continue
+		}
+
+		e.states[stateKey{rule: s.Rule, key: s.Key}] = status{state: State(s.State), value: s.Value, since: s.Since}
+	}
+
+	return nil
+}
+
+func New(ctx context.Context, repo *db.Repo, cfg []conf.Alert) (*Evaluator, error) {
+	e := &Evaluator{
+		repo:    repo,
+		rules:   make([]Rule, 0, len(cfg)),
+		states:  make(map[stateKey]status),
+		started: time.Now(),
+	}
+	seen := make(map[string]struct{}, len(cfg))
+
+	for _, a := range cfg {
+		rule, err := ParseRule(a)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[rule.Name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateRuleError, rule.Name)
+		}
+
+		seen[rule.Name] = struct{}{}
+		e.rules = append(e.rules, rule)
+	}
+
+	if err := e.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
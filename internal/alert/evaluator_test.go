@@ -0,0 +1,176 @@
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Register sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+// newTestRepo returns a repository on an empty database.
+func newTestRepo(t *testing.T) *db.Repo {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "minimon.db")
+
+	schema, err := os.ReadFile("../db/queries/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := db.New(conf.SQLiteConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func load(v float64, date time.Time) monitor.Latest {
+	return monitor.Latest{Metric: "load", Type: "float", Value: v, Date: date, Interval: time.Minute}
+}
+
+func evaluate(t *testing.T, e *Evaluator, latest []monitor.Latest, now time.Time) []Transition {
+	t.Helper()
+
+	transitions := e.Evaluate(latest, now)
+
+	err := e.Save(context.Background(), transitions, func(*db.Repo) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return transitions
+}
+
+func assertStates(t *testing.T, repo *db.Repo, want ...State) {
+	t.Helper()
+
+	states, err := repo.AlertStates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(states) != len(want) {
+		t.Fatalf("got %d persisted states, want %d", len(states), len(want))
+	}
+
+	for i, s := range states {
+		if State(s.State) != want[i] {
+			t.Fatalf("persisted state %d = %s, want %s", i, s.State, want[i])
+		}
+	}
+}
+
+func TestEvaluateStale(t *testing.T) {
+	repo := newTestRepo(t)
+
+	e, err := New(context.Background(), repo, []conf.Alert{{Rule: "load > 1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := e.started.Add(time.Hour)
+
+	if got := evaluate(t, e, []monitor.Latest{load(2, now)}, now); len(got) != 1 || got[0].To != StateFiring {
+		t.Fatalf("got %+v, want firing", got)
+	}
+
+	// the collection failed since
+	now = now.Add(3 * time.Minute)
+	stale := []monitor.Latest{load(2, now.Add(-3*time.Minute))}
+
+	if got := evaluate(t, e, stale, now); len(got) != 1 || got[0].To != StateResolved || got[0].Value != 2 {
+		t.Fatalf("got %+v, want resolved", got)
+	}
+
+	assertStates(t, repo, StateResolved)
+
+	if got := evaluate(t, e, stale, now.Add(time.Minute)); len(got) != 1 || got[0].To != StateInactive {
+		t.Fatalf("got %+v, want inactive", got)
+	}
+
+	assertStates(t, repo)
+}
+
+func TestEvaluateRestoredGrace(t *testing.T) {
+	repo := newTestRepo(t)
+	cfg := []conf.Alert{{Rule: "load > 1"}}
+
+	e, err := New(context.Background(), repo, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := e.started.Add(time.Hour)
+	evaluate(t, e, []monitor.Latest{load(2, now)}, now)
+
+	// restarted, load is not collected yet
+	e, err = New(context.Background(), repo, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := monitor.Latest{Metric: "mem", Type: "float", Value: 0.0, Interval: time.Minute}
+
+	now = e.started.Add(time.Minute)
+	other.Date = now
+
+	if got := evaluate(t, e, []monitor.Latest{other}, now); len(got) != 0 {
+		t.Fatalf("got %+v within grace, want none", got)
+	}
+
+	now = e.started.Add(3 * time.Minute)
+	other.Date = now
+
+	if got := evaluate(t, e, []monitor.Latest{other}, now); len(got) != 1 || got[0].To != StateResolved {
+		t.Fatalf("got %+v, want resolved", got)
+	}
+}
+
+func TestSaveRollback(t *testing.T) {
+	repo := newTestRepo(t)
+
+	e, err := New(context.Background(), repo, []conf.Alert{{Rule: "load > 1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := e.started.Add(time.Hour)
+	latest := []monitor.Latest{load(2, now)}
+	errQueue := errors.New("queue failed")
+
+	err = e.Save(context.Background(), e.Evaluate(latest, now), func(*db.Repo) error { return errQueue })
+	if !errors.Is(err, errQueue) {
+		t.Fatalf("got %v, want %v", err, errQueue)
+	}
+
+	assertStates(t, repo)
+
+	// the transition is raised again
+	if got := evaluate(t, e, latest, now); len(got) != 1 || got[0].To != StateFiring {
+		t.Fatalf("got %+v, want firing", got)
+	}
+
+	assertStates(t, repo, StateFiring)
+}
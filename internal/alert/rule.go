@@ -0,0 +1,113 @@
+package alert
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+const (
+	shortRuleFields = 3
+	longRuleFields  = 5
+)
+
+// Rule is a parsed alert rule.
+type Rule struct {
+	Name      string
+	Key       string
+	Matchers  []monitor.Matcher
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseRule parses `<key>[{<label matchers>}] <op> <threshold> [for <duration>]`.
+func ParseRule(cfg conf.Alert) (Rule, error) {
+	sel, rest, err := monitor.CutSelector(cfg.Rule)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: %w", ErrInvalidRuleError, err)
+	}
+
+	fields := append([]string{sel.Key}, strings.Fields(rest)...)
+	if sel.Key == "" || len(fields) != shortRuleFields && len(fields) != longRuleFields {
+		return Rule{}, fmt.Errorf("%w: %q", ErrInvalidRuleError, cfg.Rule)
+	}
+
+	if _, err := path.Match(sel.Key, ""); err != nil {
+		return Rule{}, fmt.Errorf("%w: %w", ErrInvalidRuleError, err)
+	}
+
+	rule := Rule{Name: cfg.Name, Key: sel.Key, Matchers: sel.Matchers, Op: fields[1]}
+	if rule.Name == "" {
+		rule.Name = strings.Join(strings.Fields(cfg.Rule), " ")
+	}
+
+	switch rule.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return Rule{}, fmt.Errorf("%w: unknown operator %q", ErrInvalidRuleError, rule.Op)
+	}
+
+	threshold, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: threshold %q", ErrInvalidRuleError, fields[2])
+	}
+
+	rule.Threshold = threshold
+
+	if len(fields) == longRuleFields {
+		if fields[3] != "for" {
+			return Rule{}, fmt.Errorf("%w: expected \"for\", got %q", ErrInvalidRuleError, fields[3])
+		}
+
+		d, err := conf.ParseDuration(fields[4])
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %w", ErrInvalidRuleError, err)
+		}
+
+		rule.For = d
+	}
+
+	return rule, nil
+}
+
+// Matches reports whether the rule applies to the series.
+func (r Rule) Matches(key string, labels monitor.Labels) bool {
+	sel := monitor.Selector{Matchers: r.Matchers}
+	if !sel.MatchLabels(labels) {
+		return false
+	}
+
+	if strings.Contains(r.Key, "*") {
+		ok, _ := path.Match(r.Key, key)
+
+		return ok
+	}
+
+	return key == r.Key || strings.HasPrefix(key, r.Key+".")
+}
+
+// Holds reports whether the value breaks the threshold.
+func (r Rule) Holds(v float64) bool {
+	switch r.Op {
+	case ">":
+		return v > r.Threshold
+	case ">=":
+		return v >= r.Threshold
+	case "<":
+		return v < r.Threshold
+	case "<=":
+		return v <= r.Threshold
+	case "==":
+		return v == r.Threshold
+	case "!=":
+		return v != r.Threshold
+	}
+
+	return false
+}
@@ -5,34 +5,90 @@ import (
 	log "log/slog"
 	"time"
 
+	"github.com/kirill-shtrykov/minimon/internal/alert"
 	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
 	"github.com/kirill-shtrykov/minimon/internal/monitor"
+	"github.com/kirill-shtrykov/minimon/internal/notify"
 )
 
 const defaultTick = 5
 
 type Monitor struct {
-	cfg *conf.Config
-	svc *monitor.Service
+	cfg    *conf.Config
+	svc    *monitor.Service
+	sched  *monitor.Scheduler
+	alerts *alert.Evaluator
+	notify *notify.Dispatcher
 }
 
+func (m *Monitor) evaluate(ctx context.Context) {
+	transitions := m.alerts.Evaluate(m.svc.Latest(), time.Now())
+	if len(transitions) == 0 {
+		return
+	}
+
+	err := m.alerts.Save(ctx, transitions, func(tx *db.Repo) error {
+		return m.notify.Notify(ctx, tx, transitions)
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to evaluate alerts", log.Any("error", err))
+
+		return
+	}
+
+	for _, t := range transitions {
+		log.InfoContext(
+			ctx,
+			"alert",
+			log.String("rule", t.Rule),
+			log.String("key", t.Key),
+			log.Float64("value", t.Value),
+			log.String("from", string(t.From)),
+			log.String("to", string(t.To)),
+		)
+	}
+}
+
+// Run collects metrics on the scheduler and evaluates
+// alerts every tick.
 func (m *Monitor) Run(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		m.sched.Run(ctx)
+	}()
+
 	ticker := time.NewTicker(defaultTick * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
+			<-done
 			log.InfoContext(ctx, "Monitoring stopped.")
 
 			return nil
 		case <-ticker.C:
-			log.DebugContext(ctx, "Collecting metrics...")
-			m.svc.CollectAndStore(ctx)
+			log.DebugContext(ctx, "Evaluating alerts...")
+			m.evaluate(ctx)
 		}
 	}
 }
 
-func NewMonitor(cfg *conf.Config, svc *monitor.Service) *Monitor {
-	return &Monitor{cfg: cfg, svc: svc}
+func NewMonitor(
+	cfg *conf.Config,
+	svc *monitor.Service,
+	alerts *alert.Evaluator,
+	dispatcher *notify.Dispatcher,
+) *Monitor {
+	return &Monitor{
+		cfg:    cfg,
+		svc:    svc,
+		sched:  monitor.NewScheduler(svc, cfg.Scheduler),
+		alerts: alerts,
+		notify: dispatcher,
+	}
 }
@@ -3,12 +3,13 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	log "log/slog"
+	"net"
 	"net/http"
 	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/kirill-shtrykov/minimon/internal/conf"
@@ -21,67 +22,20 @@ const (
 	defaultWriteTimeout = 15 * time.Second
 	defaultIdleTimeout  = 60 * time.Second
 	defaultMinTime      = -15 * time.Minute
+	defaultAggregation  = "avg"
 )
 
-type Reading struct {
-	Value any       `json:"value"`
-	Time  time.Time `json:"time"`
+// ChartBody is uPlot data, timestamps followed by a column for
+// every series in Keys.
+type ChartBody struct {
+	Keys []string `json:"keys"`
+	Data [][]any  `json:"data"`
 }
 
-type Metric struct {
-	Name     string    `json:"name"`
-	Readings []Reading `json:"readings"`
-}
-
-type ResponseBody struct {
-	Metrics []Metric `json:"metrics"`
-}
-
-func newResponseBody(readings []monitor.Reading) ([]byte, error) {
-	metrics := make(map[string]any)
-
-	for _, r := range readings {
-		parts := strings.Split(r.Key, ".")
-		current := metrics
-
-		for i := 0; i < len(parts)-1; i++ {
-			p := parts[i]
-			if _, ok := current[p]; !ok {
-				current[p] = make(map[string]any)
-			}
-			current = current[p].(map[string]any)
-		}
-
-		last := parts[len(parts)-1]
-		_, err := strconv.Atoi(last)
-		if err != nil {
-			current[last] = map[string]any{"value": r.Value, "time": r.Date}
-			continue
-		}
-
-		if _, ok := current[last]; !ok {
-			current[last] = []any{}
-		}
-
-		arr := current[last].([]any)
-
-		current[last] = append(arr, map[string]any{
-			"value": r.Value,
-			"time":  r.Date,
-		})
-	}
-
-	out := map[string]any{"metrics": metrics}
-
-	b, err := json.Marshal(out)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
-	}
-
-	return b, nil
-}
-
-func uPlotResponse(readings []monitor.Reading) ([]byte, error) {
+// uPlotResponse returns uPlot data, timestamps followed by a column
+// for every series. The series are only named in a ChartBody if
+// withKeys is set.
+func uPlotResponse(readings []monitor.Reading, withKeys bool) ([]byte, error) {
 	timestampsMap := make(map[int64]struct{})
 	seriesMap := make(map[string]map[int64]any)
 
@@ -89,11 +43,12 @@ func uPlotResponse(readings []monitor.Reading) ([]byte, error) {
 		ts := r.Date.Unix()
 		timestampsMap[ts] = struct{}{}
 
-		if seriesMap[r.Key] == nil {
-			seriesMap[r.Key] = make(map[int64]any)
+		series := r.Series()
+		if seriesMap[series] == nil {
+			seriesMap[series] = make(map[int64]any)
 		}
 
-		seriesMap[r.Key][ts] = r.Value
+		seriesMap[series][ts] = r.Value
 	}
 
 	var timestamps []int64
@@ -102,6 +57,7 @@ func uPlotResponse(readings []monitor.Reading) ([]byte, error) {
 	}
 	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
 
+	keys := seriesKeys(readings)
 	result := [][]any{}
 	xRow := make([]any, len(timestamps))
 	for i, ts := range timestamps {
@@ -109,12 +65,6 @@ func uPlotResponse(readings []monitor.Reading) ([]byte, error) {
 	}
 	result = append(result, xRow)
 
-	keys := make([]string, 0, len(seriesMap))
-	for k := range seriesMap {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
 	for _, k := range keys {
 		row := make([]any, len(timestamps))
 		for i, ts := range timestamps {
@@ -127,7 +77,12 @@ func uPlotResponse(readings []monitor.Reading) ([]byte, error) {
 		result = append(result, row)
 	}
 
-	b, err := json.Marshal(result)
+	var body any = result
+	if withKeys {
+		body = ChartBody{Keys: keys, Data: result}
+	}
+
+	b, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal result: %w", err)
 	}
@@ -135,6 +90,25 @@ func uPlotResponse(readings []monitor.Reading) ([]byte, error) {
 	return b, nil
 }
 
+// seriesKeys returns the distinct series of the readings in the order
+// uPlotResponse lays out their columns.
+func seriesKeys(readings []monitor.Reading) []string {
+	seen := make(map[string]struct{})
+	keys := []string{}
+
+	for _, r := range readings {
+		series := r.Series()
+		if _, ok := seen[series]; !ok {
+			seen[series] = struct{}{}
+			keys = append(keys, series)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 func dateFromString(date string, def time.Time) time.Time {
 	t, err := time.Parse(time.RFC3339, date)
 	if err != nil {
@@ -190,12 +164,48 @@ func (s *Server) apiHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metric := r.PathValue("metric")
+	sel, err := monitor.ParseSelector(r.PathValue("metric"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
 	minDate := dateFromString(r.URL.Query().Get("min"), time.Now().Add(defaultMinTime))
 	maxDate := dateFromString(r.URL.Query().Get("max"), time.Now())
 	strict := boolFromString(r.URL.Query().Get("strict"))
 
-	readings, err := s.svc.Metric(r.Context(), metric, minDate, maxDate, strict)
+	var readings []monitor.Reading
+
+	if rawStep := r.URL.Query().Get("step"); rawStep != "" {
+		step, stepErr := conf.ParseDuration(rawStep)
+		if stepErr != nil {
+			http.Error(w, "Invalid step", http.StatusBadRequest)
+
+			return
+		}
+
+		agg := r.URL.Query().Get("agg")
+		if agg == "" {
+			agg = defaultAggregation
+		}
+
+		readings, err = s.svc.Aggregate(r.Context(), sel, minDate, maxDate, strict, step, agg)
+		if errors.Is(err, monitor.ErrUnknownAggregationError) || errors.Is(err, monitor.ErrInvalidStepError) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	} else {
+		if r.URL.Query().Get("agg") != "" {
+			http.Error(w, "agg requires step", http.StatusBadRequest)
+
+			return
+		}
+
+		readings, err = s.svc.Metric(r.Context(), sel, minDate, maxDate, strict)
+	}
+
 	if err != nil {
 		log.ErrorContext(r.Context(), "failed to get readings", log.Any("error", err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -203,7 +213,7 @@ func (s *Server) apiHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b, err := uPlotResponse(readings)
+	b, err := uPlotResponse(readings, boolFromString(r.URL.Query().Get("keys")))
 	if err != nil {
 		log.ErrorContext(r.Context(), "failed to create response body", log.Any("error", err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -220,12 +230,32 @@ func (s *Server) apiHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	log.DebugContext(r.Context(), "request", "method", r.Method, "URI", r.RequestURI)
+
+	if r.Method != http.MethodGet {
+		log.WarnContext(r.Context(), "unknown method", "method", r.Method)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", prometheusMIMEType)
+	w.WriteHeader(http.StatusOK)
+
+	if err := writePrometheus(w, s.svc.Latest()); err != nil {
+		log.ErrorContext(r.Context(), "failed to write metrics", log.Any("error", err))
+	}
+}
+
 func (s *Server) Run(ctx context.Context, addr string) error {
 	mux := http.NewServeMux()
 	mux.Handle("/", http.FileServer(http.Dir("../../static")))
 	mux.HandleFunc("/dashboard", s.dashboardHandler)
 	mux.HandleFunc("/api/v1/metrics", s.apiHandler)
 	mux.HandleFunc("/api/v1/metrics/{metric}", s.apiHandler)
+	mux.HandleFunc("/api/v1/stream/{metric}", s.streamHandler)
+	mux.HandleFunc("/metrics", s.metricsHandler)
 
 	srv := &http.Server{
 		Addr:              addr,
@@ -234,6 +264,8 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 		WriteTimeout:      defaultWriteTimeout,
 		IdleTimeout:       defaultIdleTimeout,
 		ReadHeaderTimeout: defaultTimeout,
+		// cancel request contexts on shutdown, so streams end
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	go func() {
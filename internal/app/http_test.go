@@ -0,0 +1,51 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+func TestUPlotResponse(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(sec, 0) }
+
+	readings := []monitor.Reading{
+		{Key: "cpu", Labels: monitor.Labels{"thread": "1"}, Value: 2, Date: at(10)},
+		{Key: "cpu", Labels: monitor.Labels{"thread": "0"}, Value: 1, Date: at(10)},
+		{Key: "cpu", Labels: monitor.Labels{"thread": "0"}, Value: 3, Date: at(20)},
+	}
+	data := [][]any{{10.0, 20.0}, {1.0, 3.0}, {2.0, nil}}
+
+	b, err := uPlotResponse(readings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bare uPlot data by default
+	var got [][]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, data) {
+		t.Fatalf("got %v, want %v", got, data)
+	}
+
+	b, err = uPlotResponse(readings, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body ChartBody
+	if err := json.Unmarshal(b, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ChartBody{Keys: []string{`cpu{thread="0"}`, `cpu{thread="1"}`}, Data: data}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("got %v, want %v", body, want)
+	}
+}
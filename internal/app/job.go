@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	log "log/slog"
+	"time"
+)
+
+// runEvery calls fn right away and then every interval until
+// the context is done.
+func runEvery(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fn(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.InfoContext(ctx, name+" stopped.")
+
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	log "log/slog"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/notify"
+)
+
+const defaultNotifyInterval = 10 * time.Second
+
+type Notifier struct {
+	interval   time.Duration
+	dispatcher *notify.Dispatcher
+}
+
+func (n *Notifier) deliver(ctx context.Context) {
+	sent, err := n.dispatcher.Deliver(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to deliver notifications", log.Int("sent", sent), log.Any("error", err))
+
+		return
+	}
+
+	if sent > 0 {
+		log.InfoContext(ctx, "notifications sent", log.Int("sent", sent))
+	}
+}
+
+func (n *Notifier) Run(ctx context.Context) error {
+	runEvery(ctx, "Notifying", n.interval, n.deliver)
+
+	return nil
+}
+
+func NewNotifier(cfg conf.Notify, dispatcher *notify.Dispatcher) *Notifier {
+	interval := time.Duration(cfg.Interval)
+	if interval <= 0 {
+		interval = defaultNotifyInterval
+	}
+
+	return &Notifier{interval: interval, dispatcher: dispatcher}
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+const (
+	metricPrefix       = "minimon_"
+	prometheusMIMEType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+type promSeries struct {
+	name   string
+	labels map[string]string
+	value  any
+}
+
+func promName(parts ...string) string {
+	var b strings.Builder
+
+	b.WriteString(metricPrefix)
+
+	for i, p := range parts {
+		if i > 0 {
+			b.WriteByte('_')
+		}
+
+		for _, r := range p {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+				b.WriteRune(r)
+			default:
+				b.WriteByte('_')
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// toPromSeries turns a dotted key into a metric name, labels are
+// exported as they are. Chips and sensors of hwmon keys become
+// labels too.
+func toPromSeries(l monitor.Latest) promSeries {
+	series := promSeries{labels: make(map[string]string, len(l.Labels)), value: l.Value}
+
+	for name, v := range l.Labels {
+		series.labels[name] = v
+	}
+
+	if rest, ok := strings.CutPrefix(l.Key(), "sensor."); ok {
+		if k := strings.SplitN(rest, ".", 3); len(k) == 3 {
+			series.name = promName("sensor", k[0])
+			series.labels["chip"] = k[1]
+			series.labels["sensor"] = k[2]
+
+			return series
+		}
+	}
+
+	series.name = promName(strings.Split(l.Key(), ".")...)
+
+	return series
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func (s promSeries) labelString() string {
+	if len(s.labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(s.labels))
+	for name := range s.labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, escapeLabel(s.labels[name]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// writePrometheus renders numeric values in the Prometheus text
+// exposition format ordered by series. String values are skipped.
+func writePrometheus(w io.Writer, latest []monitor.Latest) error {
+	series := make([]promSeries, 0, len(latest))
+
+	for _, l := range latest {
+		switch l.Value.(type) {
+		case int, float64:
+			series = append(series, toPromSeries(l))
+		}
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].name != series[j].name {
+			return series[i].name < series[j].name
+		}
+
+		return series[i].labelString() < series[j].labelString()
+	})
+
+	var b strings.Builder
+
+	for i, s := range series {
+		if i == 0 || series[i-1].name != s.name {
+			fmt.Fprintf(&b, "# TYPE %s gauge\n", s.name)
+		}
+
+		fmt.Fprintf(&b, "%s%s %v\n", s.name, s.labelString(), s.value)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+func TestPromName(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{parts: []string{"load", "1"}, want: "minimon_load_1"},
+		{parts: []string{"1m", "load"}, want: "minimon_1m_load"},
+		{parts: []string{"disk-io", "read bytes"}, want: "minimon_disk_io_read_bytes"},
+		{parts: []string{"net.eth0", "rx"}, want: "minimon_net_eth0_rx"},
+	}
+
+	for _, tt := range tests {
+		if got := promName(tt.parts...); got != tt.want {
+			t.Errorf("promName(%q) = %s, want %s", tt.parts, got, tt.want)
+		}
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	latest := []monitor.Latest{
+		{Metric: "host.name", Type: "string", Value: "web1"},
+		{Metric: "disk.used", Labels: monitor.Labels{"mountpoint": "/var"}, Type: "int", Value: 20},
+		{Metric: "load.1", Type: "float", Value: 0.52},
+		{Metric: "disk.used", Labels: monitor.Labels{"mountpoint": "/"}, Type: "int", Value: 10},
+		{Metric: "sensor.temp.coretemp", Name: "core0", Type: "float", Value: 45.5},
+		{Metric: "sensor.temp.coretemp", Name: "core1", Type: "float", Value: 47.0},
+		{Metric: "sensor.fan", Type: "int", Value: 1200},
+		{Metric: "exec.backup", Name: "3d", Labels: monitor.Labels{"job": `C:\backup "nightly"` + "\nfull"}, Type: "int", Value: -1},
+		{Metric: "web", Name: "status", Type: "string", Value: "ok"},
+	}
+
+	var b strings.Builder
+	if err := writePrometheus(&b, latest); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "metrics.prom")
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != string(want) {
+		t.Errorf("writePrometheus() =\n%s\nwant (%s)\n%s", b.String(), golden, want)
+	}
+}
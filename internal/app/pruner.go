@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	log "log/slog"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+const defaultPruneInterval = time.Hour
+
+type Pruner struct {
+	interval  time.Duration
+	retention monitor.Retention
+	svc       *monitor.Service
+}
+
+func (p *Pruner) prune(ctx context.Context) {
+	start := time.Now()
+
+	n, err := p.svc.Prune(ctx, p.retention)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to prune metrics", log.Int64("removed", n), log.Any("error", err))
+
+		return
+	}
+
+	log.InfoContext(ctx, "metrics pruned", log.Int64("removed", n), log.Duration("took", time.Since(start)))
+}
+
+func (p *Pruner) Run(ctx context.Context) error {
+	runEvery(ctx, "Pruning", p.interval, p.prune)
+
+	return nil
+}
+
+func NewPruner(cfg conf.Retention, svc *monitor.Service) (*Pruner, error) {
+	interval := time.Duration(cfg.Interval)
+	if interval <= 0 {
+		interval = defaultPruneInterval
+	}
+
+	retention, err := monitor.NewRetention(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pruner: %w", err)
+	}
+
+	return &Pruner{interval: interval, retention: retention, svc: svc}, nil
+}
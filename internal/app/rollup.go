@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	log "log/slog"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+const defaultRollupInterval = time.Minute
+
+type Roller struct {
+	interval time.Duration
+	svc      *monitor.Service
+}
+
+func (r *Roller) rollup(ctx context.Context) {
+	if err := r.svc.Rollup(ctx); err != nil {
+		log.ErrorContext(ctx, "failed to roll up metrics", log.Any("error", err))
+	}
+}
+
+func (r *Roller) Run(ctx context.Context) error {
+	runEvery(ctx, "Rollup", r.interval, r.rollup)
+
+	return nil
+}
+
+func NewRoller(cfg conf.Rollup, svc *monitor.Service) *Roller {
+	interval := time.Duration(cfg.Interval)
+	if interval <= 0 {
+		interval = defaultRollupInterval
+	}
+
+	return &Roller{interval: interval, svc: svc}
+}
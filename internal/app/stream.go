@@ -0,0 +1,117 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	log "log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+)
+
+const defaultKeepAlive = 30 * time.Second
+
+type streamEvent struct {
+	Key    string            `json:"key"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Series string            `json:"series"`
+	Time   int64             `json:"time"`
+	Value  any               `json:"value"`
+}
+
+func writeEvent(w io.Writer, r monitor.Reading) error {
+	b, err := json.Marshal(streamEvent{
+		Key:    r.Key,
+		Labels: r.Labels,
+		Series: r.Series(),
+		Time:   r.Date.Unix(),
+		Value:  r.Value,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	return nil
+}
+
+// streamHandler pushes readings of the metric as Server-Sent Events
+// until the client goes away or is dropped for falling behind.
+func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
+	log.DebugContext(r.Context(), "request", "method", r.Method, "URI", r.RequestURI)
+
+	if r.Method != http.MethodGet {
+		log.WarnContext(r.Context(), "unknown method", "method", r.Method)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// the stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.ErrorContext(r.Context(), "failed to clear write deadline", log.Any("error", err))
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+
+		return
+	}
+
+	sel, err := monitor.ParseSelector(r.PathValue("metric"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	readings, unsubscribe := s.svc.Subscribe(sel, boolFromString(r.URL.Query().Get("strict")))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		log.ErrorContext(r.Context(), "failed to flush stream", log.Any("error", err))
+
+		return
+	}
+
+	keepAlive := time.NewTicker(defaultKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case reading, ok := <-readings:
+			if !ok {
+				log.WarnContext(r.Context(), "stream subscriber dropped", log.String("remote", r.RemoteAddr))
+
+				return
+			}
+
+			err = writeEvent(w, reading)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			log.DebugContext(r.Context(), "stream closed", log.Any("error", err))
+
+			return
+		}
+	}
+}
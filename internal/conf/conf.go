@@ -1,21 +1,257 @@
 package conf
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	ErrInvalidDurationError = errors.New("invalid duration")
+	ErrUnknownFieldError    = errors.New("unknown field")
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// ParseDuration extends time.ParseDuration with leading days
+// and weeks, so "2d", "1w" and "1d12h" are valid durations.
+func ParseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"w", week}, {"d", day}} {
+		n, rest, ok := strings.Cut(s, unit.suffix)
+		if !ok {
+			continue
+		}
+
+		i, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDurationError, s)
+		}
+
+		d += time.Duration(i) * unit.size
+		s = rest
+	}
+
+	if s == "" {
+		return d, nil
+	}
+
+	rest, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidDurationError, err)
+	}
+
+	return d + rest, nil
+}
+
+// Duration is a time.Duration in the ParseDuration format.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseDuration(value.Value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
 type SQLiteConfig struct {
 	Path string `yaml:"path"`
 }
 
+// Process selects processes by executable name,
+// pidfile or command line regular expression.
+type Process struct {
+	Name    string `yaml:"name,omitempty"`
+	Pidfile string `yaml:"pidfile,omitempty"`
+	Cmdline string `yaml:"cmdline,omitempty"`
+}
+
+// Exec runs a command and parses its standard output. Output is
+// either "value" (default) for a single value, or "keyvalue" for
+// lines of `<name> <value>` stored as sub-keys.
+type Exec struct {
+	Command []string `yaml:"command"`
+	Timeout int      `yaml:"timeout,omitempty"`
+	Output  string   `yaml:"output,omitempty"`
+}
+
+// HTTP probes an URL. The response is considered up if its status
+// is one of ExpectedStatus (any 2xx or 3xx by default). Match is
+// a regular expression and Contains is a substring the body is
+// checked against.
+type HTTP struct {
+	URL                string            `yaml:"url"`
+	Method             string            `yaml:"method,omitempty"`
+	Headers            map[string]string `yaml:"headers,omitempty"`
+	Body               string            `yaml:"body,omitempty"`
+	Timeout            int               `yaml:"timeout,omitempty"`
+	ExpectedStatus     []int             `yaml:"expected_status,omitempty"`
+	Match              string            `yaml:"match,omitempty"`
+	Contains           string            `yaml:"contains,omitempty"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// TCP dials Address over Network ("tcp" by default, or "udp"),
+// optionally writes Send and waits for Expect in the response.
+type TCP struct {
+	Address string `yaml:"address"`
+	Network string `yaml:"network,omitempty"`
+	Send    string `yaml:"send,omitempty"`
+	Expect  string `yaml:"expect,omitempty"`
+	Timeout int    `yaml:"timeout,omitempty"`
+}
+
+// TLS checks the certificate of a remote Address (host:port) or
+// of a PEM File on disk. StartTLS upgrades a plain connection
+// before the handshake, only "smtp" is supported. CAFile adds
+// trusted roots for internal certificate authorities.
+type TLS struct {
+	Address    string `yaml:"address,omitempty"`
+	File       string `yaml:"file,omitempty"`
+	CAFile     string `yaml:"ca_file,omitempty"`
+	ServerName string `yaml:"server_name,omitempty"`
+	StartTLS   string `yaml:"starttls,omitempty"`
+	Timeout    int    `yaml:"timeout,omitempty"`
+}
+
+// DNS queries Name as a record of Type (A, AAAA, CNAME, MX, TXT)
+// from Resolver (host, port 53 by default), both are required.
+// Expect lists answers that must all be present in the response.
+type DNS struct {
+	Resolver string   `yaml:"resolver"`
+	Name     string   `yaml:"name"`
+	Type     string   `yaml:"type,omitempty"`
+	Expect   []string `yaml:"expect,omitempty"`
+	Timeout  int      `yaml:"timeout,omitempty"`
+}
+
+// Prometheus scrapes an exporter in the text exposition format.
+// Series are selected by Match (glob, "*" for all) or Regex on the
+// metric name, one of them is required.
+// Template renders the labels (`{{.device}}`) into a key suffix,
+// by default label values are joined by dots in label name order.
+type Prometheus struct {
+	URL      string            `yaml:"url"`
+	Match    string            `yaml:"match,omitempty"`
+	Regex    string            `yaml:"regex,omitempty"`
+	Template string            `yaml:"template,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Timeout  int               `yaml:"timeout,omitempty"`
+}
+
+// Plugin runs Command as a long running plugin process speaking the
+// plugin protocol, Options are passed to it on handshake. Timeout
+// bounds the handshake.
+type Plugin struct {
+	Command []string       `yaml:"command"`
+	Options map[string]any `yaml:"options,omitempty"`
+	Timeout int            `yaml:"timeout,omitempty"`
+}
+
+// Metric is a collected metric. Settings of the collection method
+// are in the section named after it (`http:` for the http method)
+// and decoded by the method with Decode.
 type Metric struct {
 	Key      string `yaml:"key"`
 	Method   string `yaml:"method"`
 	Interval int    `yaml:"interval"`
 	Type     string `yaml:"type,omitempty"`
+	// Root overrides the procfs or sysfs mount point
+	// for internal keys read from there.
+	Root string `yaml:"root,omitempty"`
+	Unit string `yaml:"unit,omitempty"`
+
+	node *yaml.Node
+}
+
+// UnmarshalYAML decodes the metric and keeps its node for Decode.
+// Fields other than the ones of Metric and the method section,
+// like a misspelled section, are rejected.
+func (m *Metric) UnmarshalYAML(value *yaml.Node) error {
+	type plain Metric
+
+	if err := value.Decode((*plain)(m)); err != nil {
+		return err
+	}
+
+	if value.Kind == yaml.MappingNode {
+		fields := yamlFields(reflect.TypeFor[plain]())
+
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key := value.Content[i]
+			if !fields[key.Value] && key.Value != m.Method {
+				return fmt.Errorf("%w: line %d: %q in metric %s", ErrUnknownFieldError, key.Line, key.Value, m.Key)
+			}
+		}
+	}
+
+	m.node = value
+
+	return nil
+}
+
+// yamlFields returns the field names of a struct in YAML.
+func yamlFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
+
+// Decode decodes the section of the method into v, v is left
+// unchanged if there is none. Fields v has not are rejected.
+func (m Metric) Decode(v any) error {
+	if m.node == nil || m.node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(m.node.Content); i += 2 {
+		if m.node.Content[i].Value != m.Method {
+			continue
+		}
+
+		// a node can't be decoded strictly, only a document
+		raw, err := yaml.Marshal(m.node.Content[i+1])
+		if err != nil {
+			return fmt.Errorf("failed to decode %s config: %w", m.Method, err)
+		}
+
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+
+		if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to decode %s config: %w", m.Method, err)
+		}
+
+		return nil
+	}
+
+	return nil
 }
 
 type Widget struct {
@@ -26,10 +262,102 @@ type Widget struct {
 	Strict bool   `yaml:"strict"`
 }
 
+// Retention configures pruning of stored values. Keys maps series
+// selectors, a glob key with optional label matchers
+// (`cpu.percent.thread{index=~"[0-3]"}`), to their own retention. The
+// matching selector with the most matchers, then the longest literal
+// key wins. A key without matchers also matches the index or object
+// appended to it (`cpu.percent.thread.*`, `disk.percent./`) as
+// keys were stored before labels. Rollups maps rollup resolutions
+// ("1m" and "1h" only) to theirs. Zero keeps values forever.
+type Retention struct {
+	Default  Duration            `yaml:"default,omitempty"`
+	Keys     map[string]Duration `yaml:"keys,omitempty"`
+	Rollups  map[string]Duration `yaml:"rollups,omitempty"`
+	Interval Duration            `yaml:"interval,omitempty"`
+	Batch    int                 `yaml:"batch,omitempty"`
+}
+
+// Rollup enables downsampled 1 minute and 1 hour aggregates
+// used for long range queries.
+type Rollup struct {
+	Enabled  bool     `yaml:"enabled"`
+	Interval Duration `yaml:"interval,omitempty"`
+}
+
+// Alert is a threshold rule `<key> <op> <threshold> [for <duration>]`,
+// e.g. `cpu.percent > 90 for 2m`. The key also matches its sub-keys
+// and may be a glob, every matched key has its own alert state.
+// A key not collected for two intervals no longer breaks the
+// threshold. Name defaults to the rule itself.
+type Alert struct {
+	Name string `yaml:"name,omitempty"`
+	Rule string `yaml:"rule"`
+}
+
+// Webhook posts alert notifications to URL. Template renders the
+// request body from the notification, JSON by default. The body is
+// sent as application/json if it is valid JSON and as text/plain
+// otherwise, a Content-Type in Headers takes precedence. Name
+// identifies queued notifications and defaults to URL.
+type Webhook struct {
+	Name     string            `yaml:"name,omitempty"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Template string            `yaml:"template,omitempty"`
+	Timeout  int               `yaml:"timeout,omitempty"`
+}
+
+// Email mails alert notifications, all transitions of one evaluation
+// in a single mail. TLS is "starttls" to require STARTTLS, "tls" for
+// implicit TLS, "none" for plain connections, by default STARTTLS is
+// used when offered. Username and Password are only sent over TLS,
+// so they can't be used with "none". Subject and Body are templates.
+type Email struct {
+	Name               string   `yaml:"name,omitempty"`
+	Host               string   `yaml:"host"`
+	Port               int      `yaml:"port,omitempty"`
+	Username           string   `yaml:"username,omitempty"`
+	Password           string   `yaml:"password,omitempty"`
+	From               string   `yaml:"from"`
+	To                 []string `yaml:"to"`
+	TLS                string   `yaml:"tls,omitempty"`
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify,omitempty"`
+	Subject            string   `yaml:"subject,omitempty"`
+	Body               string   `yaml:"body,omitempty"`
+	Timeout            int      `yaml:"timeout,omitempty"`
+}
+
+// Notify configures delivery of alert notifications. Failed
+// deliveries are retried up to Retries times, 10 if unset and
+// none if 0, waiting Backoff doubled after every attempt.
+type Notify struct {
+	Webhooks []Webhook `yaml:"webhooks,omitempty"`
+	Emails   []Email   `yaml:"emails,omitempty"`
+	Retries  *int      `yaml:"retries,omitempty"`
+	Backoff  Duration  `yaml:"backoff,omitempty"`
+	Interval Duration  `yaml:"interval,omitempty"`
+}
+
+// Scheduler collects every metric on its own timer. Workers bounds
+// concurrent collections, Timeout a single collection as far as its
+// reads can be cancelled and Jitter is the longest random delay of
+// the first collection.
+type Scheduler struct {
+	Workers int      `yaml:"workers,omitempty"`
+	Timeout Duration `yaml:"timeout,omitempty"`
+	Jitter  Duration `yaml:"jitter,omitempty"`
+}
+
 type Config struct {
 	DB        SQLiteConfig `yaml:"db"`
 	Metrics   []Metric     `yaml:"metrics"`
 	Dashboard []Widget     `yaml:"dashboard"`
+	Retention Retention    `yaml:"retention,omitempty"`
+	Rollup    Rollup       `yaml:"rollup,omitempty"`
+	Alerts    []Alert      `yaml:"alerts,omitempty"`
+	Notify    Notify       `yaml:"notify,omitempty"`
+	Scheduler Scheduler    `yaml:"scheduler,omitempty"`
 }
 
 func LoadConfig(path string) (*Config, error) {
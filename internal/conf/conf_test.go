@@ -0,0 +1,96 @@
+package conf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMetricDecode(t *testing.T) {
+	var cfg Config
+
+	err := yaml.Unmarshal([]byte(`
+metrics:
+  - key: web
+    method: http
+    interval: 30
+    unit: bool
+    http:
+      url: http://localhost/health
+      expected_status: [200]
+`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var section HTTP
+	if err := cfg.Metrics[0].Decode(&section); err != nil {
+		t.Fatal(err)
+	}
+
+	want := HTTP{URL: "http://localhost/health", ExpectedStatus: []int{200}}
+	if !reflect.DeepEqual(section, want) {
+		t.Fatalf("got %+v, want %+v", section, want)
+	}
+}
+
+func TestMetricUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		metric  string
+		section bool
+	}{
+		{
+			name:   "misspelled section",
+			metric: "{key: web, method: http, htpp: {url: http://localhost}}",
+		},
+		{
+			name:   "misspelled field",
+			metric: "{key: load.avg1, method: internal, intreval: 5}",
+		},
+		{
+			name:    "unknown section field",
+			metric:  "{key: web, method: http, http: {url: http://localhost, timout: 5}}",
+			section: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Metric
+
+			err := yaml.Unmarshal([]byte(tt.metric), &m)
+			if !tt.section {
+				if !errors.Is(err, ErrUnknownFieldError) {
+					t.Fatalf("got %v, want %v", err, ErrUnknownFieldError)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var section HTTP
+			if err := m.Decode(&section); err == nil || !strings.Contains(err.Error(), "timout") {
+				t.Fatalf("got %v, want unknown timout", err)
+			}
+		})
+	}
+}
+
+func TestMetricNoSection(t *testing.T) {
+	var m Metric
+	if err := yaml.Unmarshal([]byte("{key: load.avg1, method: internal}"), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	section := Process{Name: "unchanged"}
+	if err := m.Decode(&section); err != nil || section.Name != "unchanged" {
+		t.Fatalf("got %+v, %v", section, err)
+	}
+}
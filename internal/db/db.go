@@ -3,7 +3,9 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kirill-shtrykov/minimon/internal/conf"
@@ -21,7 +23,7 @@ func (r *Repo) Metric(
 	minDate time.Time,
 	maxDate time.Time,
 	strict bool,
-) ([]generated.Metric, error) {
+) ([]generated.MetricRow, error) {
 	if !strict {
 		key = key + "%"
 	}
@@ -39,16 +41,50 @@ func (r *Repo) MetricsByDate(
 	ctx context.Context,
 	minDate time.Time,
 	maxDate time.Time,
-) ([]generated.Metric, error) {
-	m, err := r.queries.MetricsByDate(ctx,
+) ([]generated.MetricRow, error) {
+	rows, err := r.queries.MetricsByDate(ctx,
 		generated.MetricsByDateParams{MinDate: minDate, MaxDate: maxDate})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get metric: %w", err)
 	}
 
+	m := make([]generated.MetricRow, len(rows))
+	for i, row := range rows {
+		m[i] = generated.MetricRow(row)
+	}
+
 	return m, nil
 }
 
+// MetricBuckets aggregates numeric values into buckets of step seconds.
+func (r *Repo) MetricBuckets(
+	ctx context.Context,
+	arg generated.MetricBucketsParams,
+	strict bool,
+) ([]generated.MetricBucketsRow, error) {
+	if !strict {
+		arg.Key = arg.Key + "%"
+	}
+
+	rows, err := r.queries.MetricBuckets(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metric buckets: %w", err)
+	}
+
+	return rows, nil
+}
+
+// SeriesID returns the ID of the series, creating it if needed.
+// Type and unit of an existing series are updated.
+func (r *Repo) SeriesID(ctx context.Context, arg generated.UpsertSeriesParams) (int64, error) {
+	id, err := r.queries.UpsertSeries(ctx, arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get series: %w", err)
+	}
+
+	return id, nil
+}
+
 func (r *Repo) AddValue(
 	ctx context.Context,
 	arg generated.AddValueParams,
@@ -61,8 +97,301 @@ func (r *Repo) AddValue(
 	return nil
 }
 
+// ListSeries returns every stored series.
+func (r *Repo) ListSeries(ctx context.Context) ([]generated.Series, error) {
+	series, err := r.queries.ListSeries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+
+	return series, nil
+}
+
+// UnlabeledKeys returns the keys of series without labels.
+func (r *Repo) UnlabeledKeys(ctx context.Context) ([]string, error) {
+	keys, err := r.queries.UnlabeledKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Relabel moves values, rollups and alert states of the key without
+// labels to the series of newKey with labels in a single transaction
+// and deletes the old series. Alert states are keyed by series, newKey
+// followed by labels.
+func (r *Repo) Relabel(ctx context.Context, key string, newKey string, labels string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := relabel(ctx, r.queries.WithTx(tx), key, newKey, labels); err != nil {
+		_ = tx.Rollback()
+
+		return fmt.Errorf("failed to relabel %s: %w", key, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit relabel: %w", err)
+	}
+
+	return nil
+}
+
+func relabel(ctx context.Context, q *generated.Queries, key string, newKey string, labels string) error {
+	old, err := q.Series(ctx, generated.SeriesParams{Key: key, Labels: ""})
+	if err != nil {
+		return err
+	}
+
+	id, err := q.UpsertSeries(ctx, generated.UpsertSeriesParams{
+		Key:    newKey,
+		Labels: labels,
+		Type:   old.Type,
+		Unit:   old.Unit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := q.MoveValues(ctx, generated.MoveValuesParams{NewSeriesID: id, SeriesID: old.ID}); err != nil {
+		return err
+	}
+
+	if err := q.MoveRollups(ctx, generated.MoveRollupsParams{NewSeriesID: id, SeriesID: old.ID}); err != nil {
+		return err
+	}
+
+	if err := q.DeleteSeries(ctx, old.ID); err != nil {
+		return err
+	}
+
+	return q.RelabelAlertState(ctx, generated.RelabelAlertStateParams{NewKey: newKey + labels, Key: key})
+}
+
+// DeleteBefore removes at most batch values of the series older
+// than maxDate and returns the number of removed rows.
+func (r *Repo) DeleteBefore(
+	ctx context.Context,
+	seriesID int64,
+	maxDate time.Time,
+	batch int,
+) (int64, error) {
+	n, err := r.queries.DeleteBefore(ctx,
+		generated.DeleteBeforeParams{SeriesID: seriesID, MaxDate: maxDate, Batch: int64(batch)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete values: %w", err)
+	}
+
+	return n, nil
+}
+
+// OldestDate returns the date of the oldest value,
+// ok is false if there are no values.
+func (r *Repo) OldestDate(ctx context.Context) (time.Time, bool, error) {
+	date, err := r.queries.OldestDate(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get oldest date: %w", err)
+	}
+
+	return date, true, nil
+}
+
+func (r *Repo) Rollup(
+	ctx context.Context,
+	resolution int64,
+	key string,
+	minBucket int64,
+	maxBucket int64,
+	strict bool,
+) ([]generated.RollupRow, error) {
+	if !strict {
+		key = key + "%"
+	}
+
+	rollups, err := r.queries.Rollup(ctx, generated.RollupParams{
+		Resolution: resolution,
+		Key:        key,
+		MinBucket:  minBucket,
+		MaxBucket:  maxBucket,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup: %w", err)
+	}
+
+	return rollups, nil
+}
+
+// RollupRange returns the oldest and the newest buckets
+// of the resolution, both are zero if there are none.
+func (r *Repo) RollupRange(ctx context.Context, resolution int64) (int64, int64, error) {
+	row, err := r.queries.RollupRange(ctx, resolution)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rollup range: %w", err)
+	}
+
+	return row.MinBucket, row.MaxBucket, nil
+}
+
+// RollupBuckets aggregates rollups of the resolution
+// into buckets of step seconds.
+func (r *Repo) RollupBuckets(
+	ctx context.Context,
+	arg generated.RollupBucketsParams,
+	strict bool,
+) ([]generated.RollupBucketsRow, error) {
+	if !strict {
+		arg.Key = arg.Key + "%"
+	}
+
+	rows, err := r.queries.RollupBuckets(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup buckets: %w", err)
+	}
+
+	return rows, nil
+}
+
+// RollupRaw aggregates numeric values in [MinDate, MaxDate) range into
+// buckets of the resolution. Dates are compared as wall clock in the
+// database format.
+func (r *Repo) RollupRaw(ctx context.Context, arg generated.RollupRawParams) error {
+	if err := r.queries.RollupRaw(ctx, arg); err != nil {
+		return fmt.Errorf("failed to roll up: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repo) RollupFrom(ctx context.Context, arg generated.RollupFromParams) error {
+	if err := r.queries.RollupFrom(ctx, arg); err != nil {
+		return fmt.Errorf("failed to roll up: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRollupsBefore removes at most batch buckets of the resolution
+// older than maxBucket and returns the number of removed rows.
+func (r *Repo) DeleteRollupsBefore(
+	ctx context.Context,
+	resolution int64,
+	maxBucket int64,
+	batch int,
+) (int64, error) {
+	n, err := r.queries.DeleteRollupsBefore(ctx, generated.DeleteRollupsBeforeParams{
+		Resolution: resolution,
+		MaxBucket:  maxBucket,
+		Batch:      int64(batch),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rollups: %w", err)
+	}
+
+	return n, nil
+}
+
+// Tx runs fn with a repository on a single transaction, committed
+// if fn succeeds and rolled back otherwise. The repository passed
+// to fn must not start another transaction.
+func (r *Repo) Tx(ctx context.Context, fn func(tx *Repo) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(&Repo{queries: r.queries.WithTx(tx)}); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repo) AlertStates(ctx context.Context) ([]generated.AlertState, error) {
+	states, err := r.queries.AlertStates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert states: %w", err)
+	}
+
+	return states, nil
+}
+
+func (r *Repo) SetAlertState(ctx context.Context, arg generated.UpsertAlertStateParams) error {
+	if err := r.queries.UpsertAlertState(ctx, arg); err != nil {
+		return fmt.Errorf("failed to set alert state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repo) DeleteAlertState(ctx context.Context, rule string, key string) error {
+	err := r.queries.DeleteAlertState(ctx, generated.DeleteAlertStateParams{Rule: rule, Key: key})
+	if err != nil {
+		return fmt.Errorf("failed to delete alert state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repo) Enqueue(ctx context.Context, arg generated.EnqueueParams) error {
+	if err := r.queries.Enqueue(ctx, arg); err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repo) DueNotifications(ctx context.Context, now time.Time, batch int64) ([]generated.Outbox, error) {
+	due, err := r.queries.DueNotifications(ctx, generated.DueNotificationsParams{Now: now, Batch: batch})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due notifications: %w", err)
+	}
+
+	return due, nil
+}
+
+func (r *Repo) RetryNotification(ctx context.Context, arg generated.RetryNotificationParams) error {
+	if err := r.queries.RetryNotification(ctx, arg); err != nil {
+		return fmt.Errorf("failed to reschedule notification: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repo) DeleteNotification(ctx context.Context, id int64) error {
+	if err := r.queries.DeleteNotification(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+
+	return nil
+}
+
+// dsn adds the connection parameters to the database path: readers
+// don't block the writer, and writers wait for each other instead of
+// failing with SQLITE_BUSY.
+func dsn(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	return path + sep + "_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+}
+
 func New(cfg conf.SQLiteConfig) (*Repo, error) {
-	db, err := sql.Open("sqlite3", cfg.Path)
+	db, err := sql.Open("sqlite3", dsn(cfg.Path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db/generated"
+)
+
+func TestConcurrentWrites(t *testing.T) {
+	const writers, writes = 8, 20
+
+	r, err := New(conf.SQLiteConfig{Path: filepath.Join(t.TempDir(), "minimon.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { r.db.Close() })
+
+	if _, err := r.db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var mode string
+	if err := r.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("got journal mode %q, %v", mode, err)
+	}
+
+	ctx := context.Background()
+	errs := make(chan error, writers*writes)
+
+	var wg sync.WaitGroup
+
+	for range writers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range writes {
+				errs <- r.Tx(ctx, func(tx *Repo) error {
+					if _, err := tx.DueNotifications(ctx, time.Now(), 1); err != nil {
+						return err
+					}
+
+					return tx.Enqueue(ctx, generated.EnqueueParams{Channel: "test", Payload: []byte("[]"), NextAttempt: time.Now()})
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&n); err != nil || n != writers*writes {
+		t.Fatalf("got %d notifications, %v", n, err)
+	}
+}
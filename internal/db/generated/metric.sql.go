@@ -7,37 +7,71 @@ package generated
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
 const addValue = `-- name: AddValue :one
 INSERT INTO metric (
-    key, type, value
+    series_id, int_value, real_value, text_value
 ) VALUES (
-    ?, ?, ?
+    ?, ?, ?, ?
 )
 RETURNING id
 `
 
 type AddValueParams struct {
-	Key   string
-	Type  string
-	Value []byte
+	SeriesID  int64
+	IntValue  sql.NullInt64
+	RealValue sql.NullFloat64
+	TextValue sql.NullString
 }
 
 func (q *Queries) AddValue(ctx context.Context, arg AddValueParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, addValue, arg.Key, arg.Type, arg.Value)
+	row := q.db.QueryRowContext(ctx, addValue,
+		arg.SeriesID,
+		arg.IntValue,
+		arg.RealValue,
+		arg.TextValue,
+	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const deleteBefore = `-- name: DeleteBefore :execrows
+DELETE FROM metric
+WHERE id IN (
+    SELECT id
+    FROM metric
+    WHERE series_id = ?
+      AND date < ?2
+    LIMIT ?3
+)
+`
+
+type DeleteBeforeParams struct {
+	SeriesID int64
+	MaxDate  time.Time
+	Batch    int64
+}
+
+func (q *Queries) DeleteBefore(ctx context.Context, arg DeleteBeforeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBefore, arg.SeriesID, arg.MaxDate, arg.Batch)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const metric = `-- name: Metric :many
-SELECT id, "key", type, value, date
+SELECT metric.id, series.key, series.labels, series.type,
+       metric.int_value, metric.real_value, metric.text_value, metric.date
 FROM metric
-WHERE key LIKE ?
-  AND date > ?2
-  AND date < ?3
+JOIN series ON series.id = metric.series_id
+WHERE series.key LIKE ?
+  AND metric.date > ?2
+  AND metric.date < ?3
 `
 
 type MetricParams struct {
@@ -46,20 +80,34 @@ type MetricParams struct {
 	MaxDate time.Time
 }
 
-func (q *Queries) Metric(ctx context.Context, arg MetricParams) ([]Metric, error) {
+type MetricRow struct {
+	ID        int64
+	Key       string
+	Labels    string
+	Type      string
+	IntValue  sql.NullInt64
+	RealValue sql.NullFloat64
+	TextValue sql.NullString
+	Date      time.Time
+}
+
+func (q *Queries) Metric(ctx context.Context, arg MetricParams) ([]MetricRow, error) {
 	rows, err := q.db.QueryContext(ctx, metric, arg.Key, arg.MinDate, arg.MaxDate)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Metric
+	var items []MetricRow
 	for rows.Next() {
-		var i Metric
+		var i MetricRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Key,
+			&i.Labels,
 			&i.Type,
-			&i.Value,
+			&i.IntValue,
+			&i.RealValue,
+			&i.TextValue,
 			&i.Date,
 		); err != nil {
 			return nil, err
@@ -75,11 +123,84 @@ func (q *Queries) Metric(ctx context.Context, arg MetricParams) ([]Metric, error
 	return items, nil
 }
 
+const metricBuckets = `-- name: MetricBuckets :many
+SELECT series.key,
+       series.labels,
+       CAST(CAST(strftime('%s', metric.date) AS INTEGER) / ?1 * ?1 AS INTEGER) AS step_bucket,
+       CAST(MIN(COALESCE(metric.real_value, metric.int_value)) AS REAL) AS min,
+       CAST(MAX(COALESCE(metric.real_value, metric.int_value)) AS REAL) AS max,
+       CAST(SUM(COALESCE(metric.real_value, metric.int_value)) AS REAL) AS sum,
+       CAST(COUNT(*) AS INTEGER) AS count
+FROM metric
+JOIN series ON series.id = metric.series_id
+WHERE series.key LIKE ?2
+  AND metric.date > ?3
+  AND metric.date < ?4
+  AND COALESCE(metric.real_value, metric.int_value) IS NOT NULL
+GROUP BY metric.series_id, step_bucket
+ORDER BY step_bucket
+`
+
+type MetricBucketsParams struct {
+	Step    int64
+	Key     string
+	MinDate time.Time
+	MaxDate time.Time
+}
+
+type MetricBucketsRow struct {
+	Key        string
+	Labels     string
+	StepBucket int64
+	Min        float64
+	Max        float64
+	Sum        float64
+	Count      int64
+}
+
+func (q *Queries) MetricBuckets(ctx context.Context, arg MetricBucketsParams) ([]MetricBucketsRow, error) {
+	rows, err := q.db.QueryContext(ctx, metricBuckets,
+		arg.Step,
+		arg.Key,
+		arg.MinDate,
+		arg.MaxDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MetricBucketsRow
+	for rows.Next() {
+		var i MetricBucketsRow
+		if err := rows.Scan(
+			&i.Key,
+			&i.Labels,
+			&i.StepBucket,
+			&i.Min,
+			&i.Max,
+			&i.Sum,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const metricsByDate = `-- name: MetricsByDate :many
-SELECT id, "key", type, value, date
+SELECT metric.id, series.key, series.labels, series.type,
+       metric.int_value, metric.real_value, metric.text_value, metric.date
 FROM metric
-WHERE date > ?1
-  AND date < ?2
+JOIN series ON series.id = metric.series_id
+WHERE metric.date > ?1
+  AND metric.date < ?2
 `
 
 type MetricsByDateParams struct {
@@ -87,20 +208,34 @@ type MetricsByDateParams struct {
 	MaxDate time.Time
 }
 
-func (q *Queries) MetricsByDate(ctx context.Context, arg MetricsByDateParams) ([]Metric, error) {
+type MetricsByDateRow struct {
+	ID        int64
+	Key       string
+	Labels    string
+	Type      string
+	IntValue  sql.NullInt64
+	RealValue sql.NullFloat64
+	TextValue sql.NullString
+	Date      time.Time
+}
+
+func (q *Queries) MetricsByDate(ctx context.Context, arg MetricsByDateParams) ([]MetricsByDateRow, error) {
 	rows, err := q.db.QueryContext(ctx, metricsByDate, arg.MinDate, arg.MaxDate)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Metric
+	var items []MetricsByDateRow
 	for rows.Next() {
-		var i Metric
+		var i MetricsByDateRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Key,
+			&i.Labels,
 			&i.Type,
-			&i.Value,
+			&i.IntValue,
+			&i.RealValue,
+			&i.TextValue,
 			&i.Date,
 		); err != nil {
 			return nil, err
@@ -115,3 +250,33 @@ func (q *Queries) MetricsByDate(ctx context.Context, arg MetricsByDateParams) ([
 	}
 	return items, nil
 }
+
+const moveValues = `-- name: MoveValues :exec
+UPDATE metric
+SET series_id = ?1
+WHERE series_id = ?2
+`
+
+type MoveValuesParams struct {
+	NewSeriesID int64
+	SeriesID    int64
+}
+
+func (q *Queries) MoveValues(ctx context.Context, arg MoveValuesParams) error {
+	_, err := q.db.ExecContext(ctx, moveValues, arg.NewSeriesID, arg.SeriesID)
+	return err
+}
+
+const oldestDate = `-- name: OldestDate :one
+SELECT date
+FROM metric
+ORDER BY date
+LIMIT 1
+`
+
+func (q *Queries) OldestDate(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, oldestDate)
+	var date time.Time
+	err := row.Scan(&date)
+	return date, err
+}
@@ -5,13 +5,50 @@
 package generated
 
 import (
+	"database/sql"
 	"time"
 )
 
-type Metric struct {
-	ID    int64
+type AlertState struct {
+	Rule  string
 	Key   string
-	Type  string
-	Value []byte
-	Date  time.Time
+	State string
+	Value float64
+	Since time.Time
+}
+
+type Metric struct {
+	ID        int64
+	SeriesID  int64
+	IntValue  sql.NullInt64
+	RealValue sql.NullFloat64
+	TextValue sql.NullString
+	Date      time.Time
+}
+
+type Outbox struct {
+	ID          int64
+	Channel     string
+	Payload     []byte
+	Attempts    int64
+	NextAttempt time.Time
+	LastError   string
+}
+
+type Rollup struct {
+	SeriesID   int64
+	Resolution int64
+	Bucket     int64
+	Min        float64
+	Avg        float64
+	Max        float64
+	Count      int64
+}
+
+type Series struct {
+	ID     int64
+	Key    string
+	Type   string
+	Unit   string
+	Labels string
 }
@@ -0,0 +1,315 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const migrateBatch = 10000
+
+//go:embed queries/schema.sql
+var schema string
+
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func hasColumn(ctx context.Context, db execQuerier, table string, column string) (bool, error) {
+	var n int
+
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+
+	return n > 0, nil
+}
+
+// Migrate converts a database storing the key, the type and an encoded
+// value on every row to series with typed values in place. It reports
+// whether the database was converted, a converted one is left as is.
+func (r *Repo) Migrate(ctx context.Context) (bool, error) {
+	legacy, err := hasColumn(ctx, r.db, "metric", "value")
+	if err != nil || !legacy {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := migrate(ctx, tx); err != nil {
+		_ = tx.Rollback()
+
+		return false, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration: %w", err)
+	}
+
+	// give the space of the legacy tables back
+	if _, err := r.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return true, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+
+	return true, nil
+}
+
+// schemaTable matches the table a schema statement creates
+// or indexes.
+func schemaTable() *regexp.Regexp {
+	return regexp.MustCompile(`^CREATE (?:TABLE (\w+)|(?:UNIQUE )?INDEX \w+ ON (\w+))`)
+}
+
+func hasTable(ctx context.Context, db execQuerier, table string) (bool, error) {
+	var n int
+
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to get table %s: %w", table, err)
+	}
+
+	return n > 0, nil
+}
+
+// missingSchema returns the statements of schema.sql creating the
+// tables that don't exist and their indexes.
+func missingSchema(ctx context.Context, db execQuerier) ([]string, error) {
+	var lines []string
+
+	for _, line := range strings.Split(schema, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var stmts []string
+
+	created := make(map[string]bool)
+	re := schemaTable()
+
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		stmt = strings.TrimSpace(stmt)
+
+		m := re.FindStringSubmatch(stmt)
+		if m == nil {
+			continue
+		}
+
+		table := m[1] + m[2]
+
+		if m[1] != "" {
+			ok, err := hasTable(ctx, db, table)
+			if err != nil {
+				return nil, err
+			}
+
+			created[table] = !ok
+		}
+
+		if created[table] {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	return stmts, nil
+}
+
+// labelsOf returns the labels column of the legacy table as qualified
+// by alias, an empty literal for tables created before labels.
+func labelsOf(ctx context.Context, tx *sql.Tx, table string, alias string) (string, error) {
+	ok, err := hasColumn(ctx, tx, table, "labels")
+	if err != nil || !ok {
+		return "''", err
+	}
+
+	return alias + ".labels", nil
+}
+
+func migrate(ctx context.Context, tx *sql.Tx) error {
+	labels, err := labelsOf(ctx, tx, "metric", "l")
+	if err != nil {
+		return err
+	}
+
+	rollups, err := hasColumn(ctx, tx, "rollup", "key")
+	if err != nil {
+		return err
+	}
+
+	rollupLabels, err := labelsOf(ctx, tx, "rollup", "r")
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		"DROP INDEX IF EXISTS metric_key_date",
+		"DROP INDEX IF EXISTS metric_date",
+		"ALTER TABLE metric RENAME TO legacy_metric",
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	if rollups {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE rollup RENAME TO legacy_rollup"); err != nil {
+			return fmt.Errorf("failed to rename legacy rollups: %w", err)
+		}
+	}
+
+	stmts, err := missingSchema(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	stmts = append(stmts,
+		// the type of a series is the type of its latest value
+		`INSERT INTO series (key, labels, type)
+		SELECT l.key, `+labels+`, l.type
+		FROM legacy_metric AS l
+		WHERE l.id IN (SELECT MAX(l.id) FROM legacy_metric AS l GROUP BY l.key, `+labels+`)`,
+	)
+
+	if rollups {
+		stmts = append(stmts,
+			// rolled up values may have been removed already
+			`INSERT OR IGNORE INTO series (key, labels, type)
+			SELECT DISTINCT r.key, `+rollupLabels+`, 'float'
+			FROM legacy_rollup AS r`,
+			`INSERT INTO rollup (series_id, resolution, bucket, min, avg, max, count)
+			SELECT series.id, r.resolution, r.bucket, r.min, r.avg, r.max, r.count
+			FROM legacy_rollup AS r
+			JOIN series ON series.key = r.key AND series.labels = `+rollupLabels,
+			"DROP TABLE legacy_rollup",
+		)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	if err := migrateValues(ctx, tx, labels); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DROP TABLE legacy_metric"); err != nil {
+		return fmt.Errorf("failed to drop legacy values: %w", err)
+	}
+
+	return nil
+}
+
+// migrateValues copies legacy values in batches decoding them into the
+// column of their type. Values that can not be decoded and floats that
+// are not finite are copied as NULL.
+func migrateValues(ctx context.Context, tx *sql.Tx, labels string) error {
+	insert, err := tx.PrepareContext(ctx,
+		"INSERT INTO metric (id, series_id, int_value, real_value, text_value, date) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer insert.Close()
+
+	var last int64
+
+	for {
+		n, err := migrateBatchFrom(ctx, tx, insert, labels, &last)
+		if err != nil {
+			return err
+		}
+
+		if n < migrateBatch {
+			return nil
+		}
+	}
+}
+
+type legacyValue struct {
+	id       int64
+	seriesID int64
+	typ      string
+	value    []byte
+	date     sql.NullString
+}
+
+func migrateBatchFrom(ctx context.Context, tx *sql.Tx, insert *sql.Stmt, labels string, last *int64) (int, error) {
+	// dates are copied as text to keep their wall clock format
+	rows, err := tx.QueryContext(ctx, `SELECT l.id, series.id, l.type, l.value, CAST(l.date AS TEXT)
+		FROM legacy_metric AS l
+		JOIN series ON series.key = l.key AND series.labels = `+labels+`
+		WHERE l.id > ?
+		ORDER BY l.id
+		LIMIT ?`, *last, migrateBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get legacy values: %w", err)
+	}
+
+	var values []legacyValue
+
+	for rows.Next() {
+		var v legacyValue
+		if err := rows.Scan(&v.id, &v.seriesID, &v.typ, &v.value, &v.date); err != nil {
+			_ = rows.Close()
+
+			return 0, fmt.Errorf("failed to get legacy values: %w", err)
+		}
+
+		values = append(values, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get legacy values: %w", err)
+	}
+
+	for _, v := range values {
+		i, f, s := decodeLegacy(v.value, v.typ)
+
+		if _, err := insert.ExecContext(ctx, v.id, v.seriesID, i, f, s, v.date); err != nil {
+			return 0, fmt.Errorf("failed to copy value %d: %w", v.id, err)
+		}
+
+		*last = v.id
+	}
+
+	return len(values), nil
+}
+
+// decodeLegacy decodes a value of the type encoded as 8 little endian
+// bytes for numbers, integers in two's complement.
+func decodeLegacy(b []byte, typ string) (sql.NullInt64, sql.NullFloat64, sql.NullString) {
+	var (
+		i sql.NullInt64
+		f sql.NullFloat64
+		s sql.NullString
+	)
+
+	const size = 8
+
+	switch {
+	case typ == "string":
+		s = sql.NullString{String: string(b), Valid: true}
+	case len(b) != size:
+	case typ == "int":
+		i = sql.NullInt64{Int64: int64(binary.LittleEndian.Uint64(b)), Valid: true}
+	case typ == "float":
+		if v := math.Float64frombits(binary.LittleEndian.Uint64(b)); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			f = sql.NullFloat64{Float64: v, Valid: true}
+		}
+	}
+
+	return i, f, s
+}
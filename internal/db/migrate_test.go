@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	// Register sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// newLegacyRepo returns a repository on a database created
+// with the schema of the fixture.
+func newLegacyRepo(t *testing.T, fixture string) *Repo {
+	t.Helper()
+
+	legacy, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(conf.SQLiteConfig{Path: filepath.Join(t.TempDir(), "minimon.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { r.db.Close() })
+
+	if _, err := r.db.Exec(string(legacy)); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func encoded(u uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, u)
+}
+
+type migrated struct {
+	Key    string
+	Labels string
+	Type   string
+	Int    sql.NullInt64
+	Real   sql.NullFloat64
+	Text   sql.NullString
+	Date   string
+}
+
+func migratedValues(t *testing.T, r *Repo) []migrated {
+	t.Helper()
+
+	rows, err := r.db.Query(`SELECT series.key, series.labels, series.type,
+		metric.int_value, metric.real_value, metric.text_value, CAST(metric.date AS TEXT)
+		FROM metric
+		JOIN series ON series.id = metric.series_id
+		ORDER BY metric.id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var values []migrated
+
+	for rows.Next() {
+		var v migrated
+		if err := rows.Scan(&v.Key, &v.Labels, &v.Type, &v.Int, &v.Real, &v.Text, &v.Date); err != nil {
+			t.Fatal(err)
+		}
+
+		values = append(values, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+func migrateOnce(t *testing.T, r *Repo) {
+	t.Helper()
+
+	for i, want := range []bool{true, false} {
+		got, err := r.Migrate(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Fatalf("migration %d reported %t, want %t", i+1, got, want)
+		}
+	}
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	r := newLegacyRepo(t, "legacy.sql")
+
+	_, err := r.db.Exec(`INSERT INTO metric (key, type, value, date) VALUES
+		('load', 'float', ?, '2026-01-01 10:00:00'),
+		('procs', 'int', ?, '2026-01-01 10:00:00'),
+		('host.name', 'string', 'web1', '2026-01-01 10:00:00'),
+		('procs', 'int', ?, '2026-01-01 10:00:05'),
+		('load', 'float', ?, '2026-01-01 10:00:05'),
+		('load', 'float', x'00', '2026-01-01 10:00:10')`,
+		encoded(math.Float64bits(0.5)), encoded(12), encoded(math.MaxUint64), encoded(math.Float64bits(math.NaN())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrateOnce(t, r)
+
+	want := []migrated{
+		{Key: "load", Type: "float", Real: sql.NullFloat64{Float64: 0.5, Valid: true}, Date: "2026-01-01 10:00:00"},
+		{Key: "procs", Type: "int", Int: sql.NullInt64{Int64: 12, Valid: true}, Date: "2026-01-01 10:00:00"},
+		{Key: "host.name", Type: "string", Text: sql.NullString{String: "web1", Valid: true}, Date: "2026-01-01 10:00:00"},
+		// ints were written in two's complement
+		{Key: "procs", Type: "int", Int: sql.NullInt64{Int64: -1, Valid: true}, Date: "2026-01-01 10:00:05"},
+		// NaN and malformed blobs become nulls
+		{Key: "load", Type: "float", Date: "2026-01-01 10:00:05"},
+		{Key: "load", Type: "float", Date: "2026-01-01 10:00:10"},
+	}
+
+	if got := migratedValues(t, r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// tables created after the baseline are added
+	for _, table := range []string{"rollup", "alert_state", "outbox"} {
+		if ok, err := hasTable(context.Background(), r.db, table); err != nil || !ok {
+			t.Fatalf("table %s missing: %v", table, err)
+		}
+	}
+}
+
+func TestMigrateLabels(t *testing.T) {
+	r := newLegacyRepo(t, "legacy_labels.sql")
+
+	_, err := r.db.Exec(`INSERT INTO metric (key, labels, type, value, date) VALUES
+		('disk.used', '{mount="/"}', 'int', ?, '2026-01-01 10:00:00'),
+		('disk.used', '{mount="/var"}', 'int', ?, '2026-01-01 10:00:00');
+		INSERT INTO rollup VALUES
+		('disk.used', '{mount="/"}', 60, 1767261600, 1, 1, 1, 1),
+		('gone', '', 60, 1767261600, 2, 2, 2, 1);
+		INSERT INTO alert_state VALUES ('disk', 'disk.used{mount="/"}', 'firing', 1, '2026-01-01 10:00:00')`,
+		encoded(1), encoded(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrateOnce(t, r)
+
+	want := []migrated{
+		{Key: "disk.used", Labels: `{mount="/"}`, Type: "int", Int: sql.NullInt64{Int64: 1, Valid: true}, Date: "2026-01-01 10:00:00"},
+		{Key: "disk.used", Labels: `{mount="/var"}`, Type: "int", Int: sql.NullInt64{Int64: 2, Valid: true}, Date: "2026-01-01 10:00:00"},
+	}
+
+	if got := migratedValues(t, r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	var rollups int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM rollup JOIN series ON series.id = rollup.series_id`).Scan(&rollups); err != nil {
+		t.Fatal(err)
+	}
+
+	if rollups != 2 {
+		t.Fatalf("got %d rollups, want 2", rollups)
+	}
+
+	states, err := r.AlertStates(context.Background())
+	if err != nil || len(states) != 1 {
+		t.Fatalf("got alert states %+v, %v", states, err)
+	}
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	r, err := New(conf.SQLiteConfig{Path: filepath.Join(t.TempDir(), "minimon.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { r.db.Close() })
+
+	if _, err := r.db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := r.Migrate(context.Background()); err != nil || got {
+		t.Fatalf("got %t, %v on the current schema", got, err)
+	}
+}
@@ -0,0 +1,24 @@
+-- name: AlertStates :many
+SELECT *
+FROM alert_state;
+
+-- name: UpsertAlertState :exec
+INSERT INTO alert_state (
+    rule, key, state, value, since
+) VALUES (
+    ?, ?, ?, ?, ?
+)
+ON CONFLICT (rule, key) DO UPDATE SET
+    state = excluded.state,
+    value = excluded.value,
+    since = excluded.since;
+
+-- name: DeleteAlertState :exec
+DELETE FROM alert_state
+WHERE rule = ?
+  AND key = ?;
+
+-- name: RelabelAlertState :exec
+UPDATE OR REPLACE alert_state
+SET key = sqlc.arg(New_Key)
+WHERE key = sqlc.arg(Key);
@@ -1,20 +1,62 @@
 -- name: Metric :many
-SELECT *
+SELECT metric.id, series.key, series.labels, series.type,
+       metric.int_value, metric.real_value, metric.text_value, metric.date
 FROM metric
-WHERE key LIKE ?
-  AND date > sqlc.arg(Min_Date)
-  AND date < sqlc.arg(Max_Date);
+JOIN series ON series.id = metric.series_id
+WHERE series.key LIKE ?
+  AND metric.date > sqlc.arg(Min_Date)
+  AND metric.date < sqlc.arg(Max_Date);
 
 -- name: MetricsByDate :many
-SELECT *
+SELECT metric.id, series.key, series.labels, series.type,
+       metric.int_value, metric.real_value, metric.text_value, metric.date
 FROM metric
-WHERE date > sqlc.arg(Min_Date)
-  AND date < sqlc.arg(Max_Date);
+JOIN series ON series.id = metric.series_id
+WHERE metric.date > sqlc.arg(Min_Date)
+  AND metric.date < sqlc.arg(Max_Date);
 
 -- name: AddValue :one
 INSERT INTO metric (
-    key, type, value
+    series_id, int_value, real_value, text_value
 ) VALUES (
-    ?, ?, ?
+    ?, ?, ?, ?
 )
 RETURNING id;
+
+-- name: DeleteBefore :execrows
+DELETE FROM metric
+WHERE id IN (
+    SELECT id
+    FROM metric
+    WHERE series_id = ?
+      AND date < sqlc.arg(Max_Date)
+    LIMIT sqlc.arg(Batch)
+);
+
+-- name: OldestDate :one
+SELECT date
+FROM metric
+ORDER BY date
+LIMIT 1;
+
+-- name: MetricBuckets :many
+SELECT series.key,
+       series.labels,
+       CAST(CAST(strftime('%s', metric.date) AS INTEGER) / sqlc.arg(Step) * sqlc.arg(Step) AS INTEGER) AS step_bucket,
+       CAST(MIN(COALESCE(metric.real_value, metric.int_value)) AS REAL) AS min,
+       CAST(MAX(COALESCE(metric.real_value, metric.int_value)) AS REAL) AS max,
+       CAST(SUM(COALESCE(metric.real_value, metric.int_value)) AS REAL) AS sum,
+       CAST(COUNT(*) AS INTEGER) AS count
+FROM metric
+JOIN series ON series.id = metric.series_id
+WHERE series.key LIKE sqlc.arg(Key)
+  AND metric.date > sqlc.arg(Min_Date)
+  AND metric.date < sqlc.arg(Max_Date)
+  AND COALESCE(metric.real_value, metric.int_value) IS NOT NULL
+GROUP BY metric.series_id, step_bucket
+ORDER BY step_bucket;
+
+-- name: MoveValues :exec
+UPDATE metric
+SET series_id = sqlc.arg(New_Series_ID)
+WHERE series_id = sqlc.arg(Series_ID);
@@ -0,0 +1,21 @@
+-- name: Enqueue :exec
+INSERT INTO outbox (channel, payload, next_attempt)
+VALUES (?, ?, ?);
+
+-- name: DueNotifications :many
+SELECT *
+FROM outbox
+WHERE next_attempt <= sqlc.arg(Now)
+ORDER BY id
+LIMIT sqlc.arg(Batch);
+
+-- name: RetryNotification :exec
+UPDATE outbox
+SET attempts = ?,
+    next_attempt = ?,
+    last_error = ?
+WHERE id = ?;
+
+-- name: DeleteNotification :exec
+DELETE FROM outbox
+WHERE id = ?;
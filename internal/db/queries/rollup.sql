@@ -0,0 +1,91 @@
+-- name: Rollup :many
+SELECT series.key, series.labels, rollup.bucket, rollup.avg
+FROM rollup
+JOIN series ON series.id = rollup.series_id
+WHERE rollup.resolution = ?
+  AND series.key LIKE ?
+  AND rollup.bucket >= sqlc.arg(Min_Bucket)
+  AND rollup.bucket < sqlc.arg(Max_Bucket)
+ORDER BY rollup.bucket;
+
+-- name: RollupRange :one
+SELECT CAST(COALESCE(MIN(bucket), 0) AS INTEGER) AS min_bucket,
+       CAST(COALESCE(MAX(bucket), 0) AS INTEGER) AS max_bucket
+FROM rollup
+WHERE resolution = ?;
+
+-- name: RollupRaw :exec
+INSERT INTO rollup (
+    series_id, resolution, bucket, min, avg, max, count
+)
+SELECT series_id,
+       CAST(sqlc.arg(Resolution) AS INTEGER),
+       CAST(strftime('%s', date) AS INTEGER) / sqlc.arg(Resolution) * sqlc.arg(Resolution) AS b,
+       MIN(COALESCE(real_value, int_value)),
+       AVG(COALESCE(real_value, int_value)),
+       MAX(COALESCE(real_value, int_value)),
+       COUNT(*)
+FROM metric
+WHERE date >= datetime(sqlc.arg(Min_Date))
+  AND date < datetime(sqlc.arg(Max_Date))
+  AND COALESCE(real_value, int_value) IS NOT NULL
+GROUP BY series_id, b
+ON CONFLICT (series_id, resolution, bucket) DO UPDATE SET
+    min = excluded.min,
+    avg = excluded.avg,
+    max = excluded.max,
+    count = excluded.count;
+
+-- name: RollupFrom :exec
+INSERT INTO rollup (
+    series_id, resolution, bucket, min, avg, max, count
+)
+SELECT series_id,
+       CAST(sqlc.arg(Resolution) AS INTEGER),
+       bucket / sqlc.arg(Resolution) * sqlc.arg(Resolution) AS b,
+       MIN(min),
+       SUM(avg * count) / SUM(count),
+       MAX(max),
+       SUM(count)
+FROM rollup
+WHERE resolution = sqlc.arg(Source)
+  AND bucket >= sqlc.arg(Min_Bucket)
+  AND bucket < sqlc.arg(Max_Bucket)
+GROUP BY series_id, b
+ON CONFLICT (series_id, resolution, bucket) DO UPDATE SET
+    min = excluded.min,
+    avg = excluded.avg,
+    max = excluded.max,
+    count = excluded.count;
+
+-- name: DeleteRollupsBefore :execrows
+DELETE FROM rollup
+WHERE rowid IN (
+    SELECT rowid
+    FROM rollup
+    WHERE resolution = ?
+      AND bucket < sqlc.arg(Max_Bucket)
+    LIMIT sqlc.arg(Batch)
+);
+
+-- name: RollupBuckets :many
+SELECT series.key,
+       series.labels,
+       CAST(rollup.bucket / sqlc.arg(Step) * sqlc.arg(Step) AS INTEGER) AS step_bucket,
+       CAST(MIN(rollup.min) AS REAL) AS min,
+       CAST(MAX(rollup.max) AS REAL) AS max,
+       CAST(SUM(rollup.avg * rollup.count) AS REAL) AS sum,
+       CAST(SUM(rollup.count) AS INTEGER) AS count
+FROM rollup
+JOIN series ON series.id = rollup.series_id
+WHERE rollup.resolution = sqlc.arg(Resolution)
+  AND series.key LIKE sqlc.arg(Key)
+  AND rollup.bucket >= sqlc.arg(Min_Bucket)
+  AND rollup.bucket < sqlc.arg(Max_Bucket)
+GROUP BY rollup.series_id, step_bucket
+ORDER BY step_bucket;
+
+-- name: MoveRollups :exec
+UPDATE OR REPLACE rollup
+SET series_id = sqlc.arg(New_Series_ID)
+WHERE series_id = sqlc.arg(Series_ID);
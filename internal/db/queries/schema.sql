@@ -1,8 +1,63 @@
--- Create "metric" table
-CREATE TABLE metric (
+-- Create "series" table, one row for every key and labels,
+-- labels are in the {name="value",...} form or empty.
+CREATE TABLE series (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     key TEXT NOT NULL,
     type TEXT NOT NULL,
-    value BLOB NOT NULL,
+    unit TEXT NOT NULL DEFAULT '',
+    labels TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX series_key_labels ON series (key, labels);
+
+-- Create "metric" table, a value is in the column of its
+-- type and all of them are NULL for non-finite floats.
+CREATE TABLE metric (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    series_id INTEGER NOT NULL REFERENCES series (id),
+    int_value INTEGER,
+    real_value REAL,
+    text_value TEXT,
     date TIMESTAMP DEFAULT (datetime('now','localtime'))
 );
+
+CREATE INDEX metric_series_date ON metric (series_id, date);
+
+CREATE INDEX metric_date ON metric (date);
+
+-- Create "rollup" table with aggregates of numeric values,
+-- bucket is the bucket start in seconds, resolution its size.
+CREATE TABLE rollup (
+    series_id INTEGER NOT NULL REFERENCES series (id),
+    resolution INTEGER NOT NULL,
+    bucket INTEGER NOT NULL,
+    min REAL NOT NULL,
+    avg REAL NOT NULL,
+    max REAL NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (series_id, resolution, bucket)
+);
+
+-- Create "alert_state" table with the state of every
+-- alert rule and key that is not inactive.
+CREATE TABLE alert_state (
+    rule TEXT NOT NULL,
+    key TEXT NOT NULL,
+    state TEXT NOT NULL,
+    value REAL NOT NULL,
+    since DATETIME NOT NULL,
+    PRIMARY KEY (rule, key)
+);
+
+-- Create "outbox" table with notifications waiting
+-- for delivery to a notification channel.
+CREATE TABLE outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel TEXT NOT NULL,
+    payload BLOB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt DATETIME NOT NULL,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_next_attempt ON outbox (next_attempt);
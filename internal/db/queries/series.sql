@@ -0,0 +1,30 @@
+-- name: UpsertSeries :one
+INSERT INTO series (
+    key, labels, type, unit
+) VALUES (
+    ?, ?, ?, ?
+)
+ON CONFLICT (key, labels) DO UPDATE SET
+    type = excluded.type,
+    unit = excluded.unit
+RETURNING id;
+
+-- name: Series :one
+SELECT *
+FROM series
+WHERE key = ?
+  AND labels = ?;
+
+-- name: ListSeries :many
+SELECT *
+FROM series
+ORDER BY id;
+
+-- name: DeleteSeries :exec
+DELETE FROM series
+WHERE id = ?;
+
+-- name: UnlabeledKeys :many
+SELECT key
+FROM series
+WHERE labels = '';
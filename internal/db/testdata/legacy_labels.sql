@@ -0,0 +1,52 @@
+-- Create "metric" table, labels are in the
+-- {name="value",...} form or empty.
+CREATE TABLE metric (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    key TEXT NOT NULL,
+    labels TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL,
+    value BLOB NOT NULL,
+    date TIMESTAMP DEFAULT (datetime('now','localtime'))
+);
+
+CREATE INDEX metric_key_date ON metric (key, date);
+
+CREATE INDEX metric_date ON metric (date);
+
+-- Create "rollup" table with aggregates of numeric values,
+-- bucket is the bucket start in seconds, resolution its size.
+CREATE TABLE rollup (
+    key TEXT NOT NULL,
+    labels TEXT NOT NULL DEFAULT '',
+    resolution INTEGER NOT NULL,
+    bucket INTEGER NOT NULL,
+    min REAL NOT NULL,
+    avg REAL NOT NULL,
+    max REAL NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (key, labels, resolution, bucket)
+);
+
+-- Create "alert_state" table with the state of every
+-- alert rule and key that is not inactive.
+CREATE TABLE alert_state (
+    rule TEXT NOT NULL,
+    key TEXT NOT NULL,
+    state TEXT NOT NULL,
+    value REAL NOT NULL,
+    since DATETIME NOT NULL,
+    PRIMARY KEY (rule, key)
+);
+
+-- Create "outbox" table with notifications waiting
+-- for delivery to a notification channel.
+CREATE TABLE outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel TEXT NOT NULL,
+    payload BLOB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt DATETIME NOT NULL,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_next_attempt ON outbox (next_attempt);
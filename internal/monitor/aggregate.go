@@ -0,0 +1,285 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/db/generated"
+)
+
+const percentile95 = 0.95
+
+func validAggregation(agg string) bool {
+	switch agg {
+	case "avg", "min", "max", "sum", "count", "last", "p95":
+		return true
+	}
+
+	return false
+}
+
+// sqlAggregation reports whether the aggregation can be computed
+// in the database from values or rollups.
+func sqlAggregation(agg string) bool {
+	switch agg {
+	case "avg", "min", "max", "sum", "count":
+		return true
+	}
+
+	return false
+}
+
+// rollupFor returns the coarsest rollup resolution the step
+// is a multiple of, or zero if there is none.
+func rollupFor(step time.Duration) time.Duration {
+	levels := rollupLevels()
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		if step%levels[i] == 0 {
+			return levels[i]
+		}
+	}
+
+	return 0
+}
+
+type bucket struct {
+	labels   Labels
+	values   []float64
+	last     any
+	lastDate time.Time
+}
+
+func (b *bucket) add(r Reading) {
+	if b.last == nil || !r.Date.Before(b.lastDate) {
+		b.last = r.Value
+		b.lastDate = r.Date
+	}
+
+	if v, ok := numeric(r.Value); ok {
+		b.values = append(b.values, v)
+	}
+}
+
+// value returns the last value or the 95th percentile of numeric
+// values, other aggregations are computed in the database.
+func (b *bucket) value(agg string) any {
+	if agg == "last" {
+		return b.last
+	}
+
+	if len(b.values) == 0 {
+		return nil
+	}
+
+	sort.Float64s(b.values)
+
+	i := int(math.Ceil(percentile95*float64(len(b.values)))) - 1
+
+	return b.values[max(i, 0)]
+}
+
+// bucketReadings aggregates readings of every key into buckets
+// of step, dated by the bucket start.
+func bucketReadings(readings []Reading, step time.Duration, agg string) []Reading {
+	sec := int64(step / time.Second)
+	buckets := make(map[bucketKey]*bucket)
+
+	for _, r := range readings {
+		k := bucketKey{key: r.Key, labels: r.Labels.String(), bucket: r.Date.Unix() / sec * sec}
+		if buckets[k] == nil {
+			buckets[k] = &bucket{labels: r.Labels}
+		}
+
+		buckets[k].add(r)
+	}
+
+	keys := make([]bucketKey, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].bucket != keys[j].bucket {
+			return keys[i].bucket < keys[j].bucket
+		}
+
+		if keys[i].key != keys[j].key {
+			return keys[i].key < keys[j].key
+		}
+
+		return keys[i].labels < keys[j].labels
+	})
+
+	result := make([]Reading, 0, len(keys))
+
+	for _, k := range keys {
+		v := buckets[k].value(agg)
+		if v == nil {
+			continue
+		}
+
+		result = append(result, Reading{Key: k.key, Labels: buckets[k].labels, Value: v, Date: fromWallUnix(k.bucket)})
+	}
+
+	return result
+}
+
+func rollupBucketValue(row generated.RollupBucketsRow, agg string) any {
+	switch agg {
+	case "min":
+		return row.Min
+	case "max":
+		return row.Max
+	case "sum":
+		return row.Sum
+	case "count":
+		return int(row.Count)
+	}
+
+	return row.Sum / float64(row.Count)
+}
+
+func (s *Service) aggregateRollups(
+	ctx context.Context,
+	resolution time.Duration,
+	split int64,
+	sel Selector,
+	minDate time.Time,
+	maxDate time.Time,
+	strict bool,
+	step time.Duration,
+	agg string,
+) ([]Reading, error) {
+	rows, err := s.repo.RollupBuckets(ctx, generated.RollupBucketsParams{
+		Step:       int64(step / time.Second),
+		Resolution: int64(resolution / time.Second),
+		Key:        sel.Key,
+		MinBucket:  wallUnix(minDate),
+		MaxBucket:  min(split, wallUnix(maxDate)),
+	}, strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate metric: %w", err)
+	}
+
+	readings, err := bucketRows(rows, sel, agg)
+	if err != nil || split >= wallUnix(maxDate) {
+		return readings, err
+	}
+
+	// buckets after split are not rolled up yet
+	tail, err := s.aggregateRaw(ctx, sel, fromWallUnix(split-1), maxDate, strict, step, agg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range tail {
+		if wallUnix(r.Date) >= split {
+			readings = append(readings, r)
+		}
+	}
+
+	return readings, nil
+}
+
+// aggregateRaw aggregates numeric values into buckets of step
+// in the database.
+func (s *Service) aggregateRaw(
+	ctx context.Context,
+	sel Selector,
+	minDate time.Time,
+	maxDate time.Time,
+	strict bool,
+	step time.Duration,
+	agg string,
+) ([]Reading, error) {
+	rows, err := s.repo.MetricBuckets(ctx, generated.MetricBucketsParams{
+		Step:    int64(step / time.Second),
+		Key:     sel.Key,
+		MinDate: minDate,
+		MaxDate: maxDate,
+	}, strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate metric: %w", err)
+	}
+
+	buckets := make([]generated.RollupBucketsRow, len(rows))
+	for i, row := range rows {
+		buckets[i] = generated.RollupBucketsRow(row)
+	}
+
+	return bucketRows(buckets, sel, agg)
+}
+
+// bucketRows returns the aggregated buckets of the series
+// matching the labels of sel.
+func bucketRows(rows []generated.RollupBucketsRow, sel Selector, agg string) ([]Reading, error) {
+	readings := make([]Reading, 0, len(rows))
+	labels := make(labelCache)
+
+	for _, row := range rows {
+		l, err := labels.parse(row.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate metric: %w", err)
+		}
+
+		if !sel.MatchLabels(l) {
+			continue
+		}
+
+		readings = append(readings, Reading{
+			Key:    row.Key,
+			Labels: l,
+			Value:  rollupBucketValue(row, agg),
+			Date:   fromWallUnix(row.StepBucket),
+		})
+	}
+
+	return readings, nil
+}
+
+// Aggregate returns readings bucketed by step and reduced with agg
+// (avg, min, max, sum, count, last, p95). Only numeric values are
+// aggregated in the database, last and p95 are computed in memory.
+func (s *Service) Aggregate(
+	ctx context.Context,
+	sel Selector,
+	minDate time.Time,
+	maxDate time.Time,
+	strict bool,
+	step time.Duration,
+	agg string,
+) ([]Reading, error) {
+	if !validAggregation(agg) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAggregationError, agg)
+	}
+
+	if step < time.Second {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStepError, step)
+	}
+
+	if sqlAggregation(agg) {
+		if resolution := rollupFor(step); s.rollups && resolution > 0 {
+			split, ok, err := s.rollupSplit(ctx, int64(resolution/time.Second), int64(step/time.Second), minDate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to aggregate metric: %w", err)
+			}
+
+			if ok {
+				return s.aggregateRollups(ctx, resolution, split, sel, minDate, maxDate, strict, step, agg)
+			}
+		}
+
+		return s.aggregateRaw(ctx, sel, minDate, maxDate, strict, step, agg)
+	}
+
+	readings, err := s.rawMetric(ctx, sel, minDate, maxDate, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	return bucketReadings(readings, step, agg), nil
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"context"
+	log "log/slog"
+	"sync"
+)
+
+const defaultSubscriberBuffer = 64
+
+type subscriber struct {
+	sel    Selector
+	strict bool
+	ch     chan Reading
+}
+
+// Broker fans out collected readings to subscribers. Every subscriber
+// has its own buffer, a subscriber whose buffer is full is dropped
+// and its channel closed so one slow client can't stall collection.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{}
+	buffer int
+}
+
+func NewBroker(buffer int) *Broker {
+	if buffer <= 0 {
+		buffer = defaultSubscriberBuffer
+	}
+
+	return &Broker{subs: make(map[*subscriber]struct{}), buffer: buffer}
+}
+
+// Subscribe returns a channel receiving readings of the series
+// selected by sel, see Selector.Matches, and a function to
+// unsubscribe. The channel is closed on unsubscribe or drop.
+func (b *Broker) Subscribe(sel Selector, strict bool) (<-chan Reading, func()) {
+	sub := &subscriber{sel: sel, strict: strict, ch: make(chan Reading, b.buffer)}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) Publish(ctx context.Context, r Reading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if !sub.sel.Matches(r.Key, r.Labels, sub.strict) {
+			continue
+		}
+
+		select {
+		case sub.ch <- r:
+		default:
+			log.WarnContext(ctx, "dropping slow subscriber", log.String("key", sub.sel.Key))
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *Broker) remove(sub *subscriber) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.ch)
+}
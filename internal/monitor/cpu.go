@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -37,8 +38,8 @@ func CPUThreads() ([]byte, error) {
 	return b, nil
 }
 
-func CPUPercent() ([]byte, error) {
-	percent, err := cpu.Percent(defaultTick, false)
+func CPUPercent(ctx context.Context) ([]byte, error) {
+	percent, err := cpu.PercentWithContext(ctx, defaultTick, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get CPU load: %w", err)
 	}
@@ -46,8 +47,8 @@ func CPUPercent() ([]byte, error) {
 	return Float64ToBytes(percent[0])
 }
 
-func CPUPerThread() ([][]byte, error) {
-	perThread, err := cpu.Percent(defaultTick, true)
+func CPUPerThread(ctx context.Context) ([][]byte, error) {
+	perThread, err := cpu.PercentWithContext(ctx, defaultTick, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get per thread load: %w", err)
 	}
@@ -66,7 +67,7 @@ func CPUPerThread() ([][]byte, error) {
 	return pt, nil
 }
 
-func CPUByKey(key string) ([][]byte, error) {
+func CPUByKey(ctx context.Context, key string) ([][]byte, error) {
 	switch key {
 	case "cpu.cores":
 		b, err := CPUCores()
@@ -89,7 +90,7 @@ func CPUByKey(key string) ([][]byte, error) {
 
 		return bs, nil
 	case "cpu.percent":
-		b, err := CPUPercent()
+		b, err := CPUPercent(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -99,7 +100,7 @@ func CPUByKey(key string) ([][]byte, error) {
 
 		return bs, nil
 	case "cpu.percent.thread":
-		bs, err := CPUPerThread()
+		bs, err := CPUPerThread(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shirou/gopsutil/disk"
+)
+
+// splitKey cuts the prefix off the key and splits the rest into
+// the stat name and the trailing object (mountpoint, device).
+// The object may contain dots, so only the first one is significant.
+func splitKey(key string, prefix string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(key, prefix)
+	if !ok {
+		return "", "", false
+	}
+
+	stat, object, ok := strings.Cut(rest, ".")
+	if !ok || stat == "" || object == "" {
+		return "", "", false
+	}
+
+	return stat, object, true
+}
+
+func DiskUsage(ctx context.Context, stat string, mountpoint string) ([]byte, error) {
+	u, err := disk.UsageWithContext(ctx, mountpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+
+	switch stat {
+	case "total":
+		return Uint64ToBytes(u.Total)
+	case "used":
+		return Uint64ToBytes(u.Used)
+	case "free":
+		return Uint64ToBytes(u.Free)
+	case "percent":
+		return Float64ToBytes(u.UsedPercent)
+	}
+
+	return nil, fmt.Errorf("%w: disk.%s", ErrUnknownKeyError, stat)
+}
+
+func DiskInodes(ctx context.Context, stat string, mountpoint string) ([]byte, error) {
+	u, err := disk.UsageWithContext(ctx, mountpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inodes usage: %w", err)
+	}
+
+	switch stat {
+	case "total":
+		return Uint64ToBytes(u.InodesTotal)
+	case "used":
+		return Uint64ToBytes(u.InodesUsed)
+	case "free":
+		return Uint64ToBytes(u.InodesFree)
+	case "percent":
+		return Float64ToBytes(u.InodesUsedPercent)
+	}
+
+	return nil, fmt.Errorf("%w: disk.inodes.%s", ErrUnknownKeyError, stat)
+}
+
+func DiskIO(ctx context.Context, stat string, device string) ([]byte, error) {
+	counters, err := disk.IOCountersWithContext(ctx, device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk I/O counters: %w", err)
+	}
+
+	c, ok := counters[device]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDeviceError, device)
+	}
+
+	switch stat {
+	case "read_bytes":
+		return Uint64ToBytes(c.ReadBytes)
+	case "write_bytes":
+		return Uint64ToBytes(c.WriteBytes)
+	case "read_ops":
+		return Uint64ToBytes(c.ReadCount)
+	case "write_ops":
+		return Uint64ToBytes(c.WriteCount)
+	case "read_time":
+		return Uint64ToBytes(c.ReadTime)
+	case "write_time":
+		return Uint64ToBytes(c.WriteTime)
+	case "io_time":
+		return Uint64ToBytes(c.IoTime)
+	case "in_progress":
+		return Uint64ToBytes(c.IopsInProgress)
+	}
+
+	return nil, fmt.Errorf("%w: disk.io.%s", ErrUnknownKeyError, stat)
+}
+
+// DiskByKey handles keys in the following forms:
+//
+//	disk.<total|used|free|percent>.<mountpoint>
+//	disk.inodes.<total|used|free|percent>.<mountpoint>
+//	disk.io.<stat>.<device>
+func DiskByKey(ctx context.Context, key string) ([][]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	if stat, device, ok := splitKey(key, "disk.io."); ok {
+		b, err = DiskIO(ctx, stat, device)
+	} else if stat, mountpoint, ok := splitKey(key, "disk.inodes."); ok {
+		b, err = DiskInodes(ctx, stat, mountpoint)
+	} else if stat, mountpoint, ok := splitKey(key, "disk."); ok {
+		b, err = DiskUsage(ctx, stat, mountpoint)
+	} else {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{b}, nil
+}
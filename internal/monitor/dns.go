@@ -0,0 +1,441 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	log "log/slog"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const (
+	dnsPort       = 53
+	dnsHeaderLen  = 12
+	dnsRRHeader   = 10
+	dnsMaxMessage = 65535
+	dnsClassIN    = 1
+	dnsFlagQR     = 1 << 15
+	dnsFlagTC     = 1 << 9
+	dnsFlagRD     = 1 << 8
+	dnsRcodeMask  = 0xf
+	dnsPointer    = 0xc0
+	maxDNSLabel   = 63
+	maxDNSName    = 255
+	maxDNSJumps   = 16
+)
+
+// dnsType is the type of a DNS record.
+type dnsType uint16
+
+const (
+	dnsTypeA     dnsType = 1
+	dnsTypeCNAME dnsType = 5
+	dnsTypeMX    dnsType = 15
+	dnsTypeTXT   dnsType = 16
+	dnsTypeAAAA  dnsType = 28
+)
+
+// parseDNSType returns the supported record type named s.
+func parseDNSType(s string) (dnsType, bool) {
+	switch strings.ToUpper(s) {
+	case "A":
+		return dnsTypeA, true
+	case "CNAME":
+		return dnsTypeCNAME, true
+	case "MX":
+		return dnsTypeMX, true
+	case "TXT":
+		return dnsTypeTXT, true
+	case "AAAA":
+		return dnsTypeAAAA, true
+	}
+
+	return 0, false
+}
+
+// DNSCollector queries a record from a DNS server and reports
+// success, latency and whether expected answers are present. Queries
+// go to the server itself, local sources like /etc/hosts are not used.
+type DNSCollector struct {
+	server  string
+	name    string
+	qtype   dnsType
+	expect  []string
+	timeout time.Duration
+}
+
+func normalizeAnswer(s string) string {
+	return strings.ToLower(strings.TrimSuffix(s, "."))
+}
+
+// dnsQuery builds a recursive query message for the name.
+func dnsQuery(id uint16, name string, qtype dnsType) ([]byte, error) {
+	msg := make([]byte, dnsHeaderLen, dnsHeaderLen+len(name)+6)
+	binary.BigEndian.PutUint16(msg, id)
+	binary.BigEndian.PutUint16(msg[2:], dnsFlagRD)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	if name = strings.TrimSuffix(name, "."); name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > maxDNSLabel {
+				return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidConfigError, name)
+			}
+
+			msg = append(msg, byte(len(label)))
+			msg = append(msg, label...)
+		}
+	}
+
+	msg = append(msg, 0)
+
+	if len(msg)-dnsHeaderLen > maxDNSName {
+		return nil, fmt.Errorf("%w: name is too long %q", ErrInvalidConfigError, name)
+	}
+
+	msg = binary.BigEndian.AppendUint16(msg, uint16(qtype))
+
+	return binary.BigEndian.AppendUint16(msg, dnsClassIN), nil
+}
+
+// readName reads a possibly compressed name at off and returns
+// it with the offset following it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+
+	end, length := -1, 0
+
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("%w: name out of message", ErrMalformedDataError)
+		}
+
+		n := int(msg[off])
+
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+
+			return strings.Join(labels, "."), end, nil
+		case n&dnsPointer == dnsPointer:
+			if off+1 >= len(msg) || jumps >= maxDNSJumps {
+				return "", 0, fmt.Errorf("%w: invalid name pointer", ErrMalformedDataError)
+			}
+
+			if end < 0 {
+				end = off + 2
+			}
+
+			off = int(binary.BigEndian.Uint16(msg[off:]) &^ (dnsPointer << 8))
+			jumps++
+		case n > maxDNSLabel || off+1+n > len(msg):
+			return "", 0, fmt.Errorf("%w: invalid label", ErrMalformedDataError)
+		case length+1+n > maxDNSName:
+			return "", 0, fmt.Errorf("%w: name is too long", ErrMalformedDataError)
+		default:
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			length += 1 + n
+			off += 1 + n
+		}
+	}
+}
+
+// rdataAnswer decodes the data of a record at off of the message.
+func rdataAnswer(msg []byte, off int, length int, qtype dnsType) (string, error) {
+	rdata := msg[off : off+length]
+
+	switch qtype {
+	case dnsTypeA, dnsTypeAAAA:
+		if length != net.IPv4len && length != net.IPv6len {
+			return "", fmt.Errorf("%w: address of %d bytes", ErrMalformedDataError, length)
+		}
+
+		return net.IP(rdata).String(), nil
+	case dnsTypeMX:
+		if length < 2 {
+			return "", fmt.Errorf("%w: MX of %d bytes", ErrMalformedDataError, length)
+		}
+
+		// preference goes first
+		off += 2
+	case dnsTypeTXT:
+		var b strings.Builder
+
+		for i := 0; i < len(rdata); i += 1 + int(rdata[i]) {
+			if i+1+int(rdata[i]) > len(rdata) {
+				return "", fmt.Errorf("%w: TXT string out of record", ErrMalformedDataError)
+			}
+
+			b.Write(rdata[i+1 : i+1+int(rdata[i])])
+		}
+
+		return b.String(), nil
+	}
+
+	name, _, err := readName(msg, off)
+	if err != nil {
+		return "", err
+	}
+
+	return normalizeAnswer(name), nil
+}
+
+func rcodeName(rcode uint16) string {
+	switch rcode {
+	case 1:
+		return "FORMERR"
+	case 2:
+		return "SERVFAIL"
+	case 3:
+		return "NXDOMAIN"
+	case 4:
+		return "NOTIMP"
+	case 5:
+		return "REFUSED"
+	}
+
+	return "rcode " + strconv.Itoa(int(rcode))
+}
+
+// parseResponse returns the answers of the queried type, other records
+// like the CNAME chain of an address are skipped.
+func parseResponse(msg []byte, qtype dnsType) ([]string, error) {
+	if len(msg) < dnsHeaderLen {
+		return nil, fmt.Errorf("%w: short DNS message", ErrMalformedDataError)
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&dnsFlagQR == 0 {
+		return nil, fmt.Errorf("%w: not a DNS response", ErrMalformedDataError)
+	}
+
+	if rcode := flags & dnsRcodeMask; rcode != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDNSQueryError, rcodeName(rcode))
+	}
+
+	off := dnsHeaderLen
+
+	for range binary.BigEndian.Uint16(msg[4:]) {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+
+		off = next + 4
+	}
+
+	var answers []string
+
+	for range binary.BigEndian.Uint16(msg[6:]) {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+
+		off = next + dnsRRHeader
+		if off > len(msg) {
+			return nil, fmt.Errorf("%w: record out of message", ErrMalformedDataError)
+		}
+
+		typ := dnsType(binary.BigEndian.Uint16(msg[off-dnsRRHeader:]))
+
+		length := int(binary.BigEndian.Uint16(msg[off-2:]))
+		if off+length > len(msg) {
+			return nil, fmt.Errorf("%w: record out of message", ErrMalformedDataError)
+		}
+
+		if typ == qtype {
+			answer, err := rdataAnswer(msg, off, length, qtype)
+			if err != nil {
+				return nil, err
+			}
+
+			answers = append(answers, answer)
+		}
+
+		off += length
+	}
+
+	return answers, nil
+}
+
+func exchangeUDP(conn net.Conn, query []byte) ([]byte, error) {
+	if _, err := conn.Write(query); err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	buf := make([]byte, dnsMaxMessage)
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		// late answers to earlier queries are skipped
+		if n >= 2 && bytes.Equal(buf[:2], query[:2]) {
+			return buf[:n], nil
+		}
+	}
+}
+
+func exchangeTCP(conn net.Conn, query []byte) ([]byte, error) {
+	msg := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+
+	if _, err := conn.Write(append(msg, query...)); err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if len(resp) < 2 || !bytes.Equal(resp[:2], query[:2]) {
+		return nil, fmt.Errorf("%w: response to another query", ErrMalformedDataError)
+	}
+
+	return resp, nil
+}
+
+func (c *DNSCollector) exchange(ctx context.Context, network string, query []byte) ([]byte, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, network, c.server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+
+	if network == "tcp" {
+		return exchangeTCP(conn, query)
+	}
+
+	return exchangeUDP(conn, query)
+}
+
+// lookup queries the server over UDP, truncated
+// responses are queried again over TCP.
+func (c *DNSCollector) lookup(ctx context.Context) ([]string, error) {
+	query, err := dnsQuery(uint16(rand.Uint32()), c.name, c.qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.exchange(ctx, "udp", query)
+	if err == nil && len(resp) >= dnsHeaderLen && binary.BigEndian.Uint16(resp[2:])&dnsFlagTC != 0 {
+		resp, err = c.exchange(ctx, "tcp", query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseResponse(resp, c.qtype)
+}
+
+func (c *DNSCollector) matches(answers []string) bool {
+	found := make(map[string]bool, len(answers))
+	for _, a := range answers {
+		found[a] = true
+	}
+
+	for _, e := range c.expect {
+		if !found[e] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *DNSCollector) Collect(ctx context.Context, m *Metric) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	answers, err := c.lookup(ctx)
+	if err != nil {
+		log.DebugContext(ctx, "DNS check failed", log.String("name", c.name), log.Any("error", err))
+
+		m.LastValue = []Value{boolValue("up", false)}
+
+		return nil
+	}
+
+	values := []Value{
+		boolValue("up", true),
+		floatValue("latency_ms", millis(time.Since(start))),
+		intValue("answers", len(answers)),
+	}
+
+	if len(c.expect) > 0 {
+		values = append(values, boolValue("match", c.matches(answers)))
+	}
+
+	m.LastValue = values
+
+	return nil
+}
+
+func NewDNSCollector(cfg conf.DNS) (*DNSCollector, error) {
+	if cfg.Name == "" || cfg.Resolver == "" {
+		return nil, fmt.Errorf("%w: name and resolver are required", ErrInvalidConfigError)
+	}
+
+	rtype := "A"
+	if cfg.Type != "" {
+		rtype = cfg.Type
+	}
+
+	qtype, ok := parseDNSType(rtype)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported record type %q", ErrInvalidConfigError, cfg.Type)
+	}
+
+	if _, err := dnsQuery(0, cfg.Name, qtype); err != nil {
+		return nil, err
+	}
+
+	c := &DNSCollector{name: cfg.Name, qtype: qtype, timeout: defaultProbeTimeout}
+
+	for _, e := range cfg.Expect {
+		if qtype == dnsTypeTXT {
+			c.expect = append(c.expect, e)
+		} else {
+			c.expect = append(c.expect, normalizeAnswer(e))
+		}
+	}
+
+	if cfg.Timeout > 0 {
+		c.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	c.server = cfg.Resolver
+	if _, _, err := net.SplitHostPort(c.server); err != nil {
+		c.server = net.JoinHostPort(c.server, strconv.Itoa(dnsPort))
+	}
+
+	return c, nil
+}
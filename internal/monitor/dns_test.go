@@ -0,0 +1,422 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// questionPointer points to the name of the question of a response.
+const questionPointer = "\xc0\x0c"
+
+func wireName(t *testing.T, name string) []byte {
+	t.Helper()
+
+	q, err := dnsQuery(0, name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return q[dnsHeaderLen : len(q)-4]
+}
+
+func record(name []byte, rtype dnsType, rdata []byte) []byte {
+	rr := append([]byte{}, name...)
+	rr = binary.BigEndian.AppendUint16(rr, uint16(rtype))
+	rr = binary.BigEndian.AppendUint16(rr, dnsClassIN)
+	rr = binary.BigEndian.AppendUint32(rr, 300)
+	rr = binary.BigEndian.AppendUint16(rr, uint16(len(rdata)))
+
+	return append(rr, rdata...)
+}
+
+// dnsKey identifies the records of a zone, type 0 holds
+// no records but makes the name known.
+type dnsKey struct {
+	name  string
+	rtype dnsType
+}
+
+// dnsStandIn answers queries over UDP and TCP on the same port from
+// a zone keyed by name and type. Responses flagged truncated over
+// UDP are answered in full over TCP.
+type dnsStandIn struct {
+	t         *testing.T
+	zone      map[dnsKey][][]byte
+	truncated map[dnsKey]bool
+	addr      string
+}
+
+func (s *dnsStandIn) answer(query []byte, tcp bool) []byte {
+	name, off, err := readName(query, dnsHeaderLen)
+	if err != nil {
+		s.t.Error(err)
+
+		return nil
+	}
+
+	key := dnsKey{name: name, rtype: dnsType(binary.BigEndian.Uint16(query[off:]))}
+
+	resp := append([]byte{}, query[:off+4]...)
+	flags := uint16(dnsFlagQR | dnsFlagRD)
+
+	records, ok := s.zone[key]
+	_, known := s.zone[dnsKey{name: name}]
+
+	switch {
+	case !ok && !known:
+		flags |= 3
+	case s.truncated[key] && !tcp:
+		flags |= dnsFlagTC
+		records = nil
+	}
+
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(records)))
+
+	for _, rr := range records {
+		resp = append(resp, rr...)
+	}
+
+	return resp
+}
+
+func (s *dnsStandIn) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, dnsMaxMessage)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		_, _ = conn.WriteTo(s.answer(buf[:n], false), addr)
+	}
+}
+
+func (s *dnsStandIn) serveTCP(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err == nil {
+			query := make([]byte, binary.BigEndian.Uint16(size[:]))
+			if _, err := io.ReadFull(conn, query); err == nil {
+				resp := s.answer(query, true)
+				_, _ = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+			}
+		}
+
+		conn.Close()
+	}
+}
+
+func newDNSStandIn(t *testing.T) *dnsStandIn {
+	t.Helper()
+
+	web := wireName(t, "web.example.test")
+	s := &dnsStandIn{
+		t: t,
+		zone: map[dnsKey][][]byte{
+			// answered by the server, not by /etc/hosts
+			{"localhost", dnsTypeA}: {record([]byte(questionPointer), dnsTypeA, []byte{192, 0, 2, 1})},
+			{"www.example.test", dnsTypeA}: {
+				record([]byte(questionPointer), dnsTypeCNAME, web),
+				record(web, dnsTypeA, []byte{192, 0, 2, 10}),
+			},
+			{"www.example.test", dnsTypeCNAME}: {record([]byte(questionPointer), dnsTypeCNAME, web)},
+			// a name without records of the type
+			{"web.example.test", 0}: nil,
+			{"v6.example.test", dnsTypeAAAA}: {
+				record([]byte(questionPointer), dnsTypeAAAA, net.ParseIP("2001:db8::1")),
+			},
+			{"example.test", dnsTypeMX}: {
+				record([]byte(questionPointer), dnsTypeMX, append([]byte{0, 10}, wireName(t, "Mail.Example.test")...)),
+				record([]byte(questionPointer), dnsTypeMX, append([]byte{0, 20}, "\x06backup"+questionPointer...)),
+			},
+			{"example.test", dnsTypeTXT}: {record([]byte(questionPointer), dnsTypeTXT, []byte("\x07v=spf1 \x04-all"))},
+			{"big.example.test", dnsTypeA}: {
+				record([]byte(questionPointer), dnsTypeA, []byte{192, 0, 2, 20}),
+			},
+		},
+		truncated: map[dnsKey]bool{{"big.example.test", dnsTypeA}: true},
+	}
+
+	var (
+		conn net.PacketConn
+		l    net.Listener
+		err  error
+	)
+
+	// the TCP port may be taken while the UDP one is not
+	for range 10 {
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l, err = net.Listen("tcp", conn.LocalAddr().String())
+		if err == nil {
+			break
+		}
+
+		conn.Close()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		l.Close()
+	})
+
+	go s.serveUDP(conn)
+	go s.serveTCP(l)
+
+	s.addr = conn.LocalAddr().String()
+
+	return s
+}
+
+func TestDNSCollect(t *testing.T) {
+	srv := newDNSStandIn(t)
+
+	tests := []struct {
+		name    string
+		rtype   string
+		expect  []string
+		answers int
+		match   int
+	}{
+		{name: "localhost", expect: []string{"192.0.2.1"}, answers: 1, match: 1},
+		{name: "www.example.test", expect: []string{"192.0.2.10"}, answers: 1, match: 1},
+		{name: "www.example.test", rtype: "cname", expect: []string{"web.example.test."}, answers: 1, match: 1},
+		{name: "web.example.test", rtype: "CNAME", expect: []string{"web.example.test"}, answers: 0, match: 0},
+		{name: "v6.example.test", rtype: "AAAA", expect: []string{"2001:DB8::1"}, answers: 1, match: 1},
+		{name: "example.test", rtype: "MX", expect: []string{"mail.example.test", "backup.example.test"}, answers: 2, match: 1},
+		{name: "example.test", rtype: "TXT", expect: []string{"v=spf1 -all"}, answers: 1, match: 1},
+		{name: "big.example.test", expect: []string{"192.0.2.20"}, answers: 1, match: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.rtype, func(t *testing.T) {
+			c, err := NewDNSCollector(conf.DNS{Resolver: srv.addr, Name: tt.name, Type: tt.rtype, Expect: tt.expect})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values := collectValues(t, c.Collect)
+			if values["up"] != 1 || values["answers"] != tt.answers || values["match"] != tt.match {
+				t.Errorf("Collect() = %v, want up with %d answers and match %d", values, tt.answers, tt.match)
+			}
+		})
+	}
+
+	c, err := NewDNSCollector(conf.DNS{Resolver: srv.addr, Name: "missing.example.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values := collectValues(t, c.Collect); values["up"] != 0 || len(values) != 1 {
+		t.Errorf("Collect() = %v, want only down for NXDOMAIN", values)
+	}
+}
+
+func TestReadName(t *testing.T) {
+	const example = "\x07example\x04test\x00"
+
+	tests := []struct {
+		name string
+		msg  string
+		off  int
+		want string
+		end  int
+	}{
+		{name: "plain", msg: "\x03www" + example, want: "www.example.test", end: 18},
+		{name: "root", msg: "\x00", want: "", end: 1},
+		{name: "pointer", msg: example + "\x03www\xc0\x00", off: 14, want: "www.example.test", end: 20},
+		{name: "pointer chain", msg: example + "\x03www\xc0\x00\x03api\xc0\x0e", off: 20, want: "api.www.example.test", end: 26},
+		{name: "cut label", msg: "\x07exam", end: -1},
+		{name: "no terminator", msg: "\x04test", end: -1},
+		{name: "cut pointer", msg: "\x03www\xc0", end: -1},
+		{name: "pointer out of message", msg: "\x03www\xc0\x20", end: -1},
+		{name: "self pointer", msg: "\xc0\x00", end: -1},
+		{name: "pointer loop", msg: "\x01a\xc0\x04\x01b\xc0\x00", end: -1},
+		{name: "long label", msg: "\x40" + strings.Repeat("a", 64) + "\x00", end: -1},
+		{name: "long name", msg: strings.Repeat("\x3f"+strings.Repeat("a", 63), 5) + "\x00", end: -1},
+		{name: "offset out of message", msg: example, off: 20, end: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, end, err := readName([]byte(tt.msg), tt.off)
+			if tt.end < 0 {
+				if !errors.Is(err, ErrMalformedDataError) {
+					t.Fatalf("readName() = %q, %v, want %v", got, err, ErrMalformedDataError)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want || end != tt.end {
+				t.Fatalf("readName() = %q, %d, %v, want %q, %d", got, end, err, tt.want, tt.end)
+			}
+		})
+	}
+}
+
+// response returns a response to a query for example.test
+// with the records as answers.
+func response(t *testing.T, qtype dnsType, flags uint16, records ...[]byte) []byte {
+	t.Helper()
+
+	resp, err := dnsQuery(1, "example.test", qtype)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary.BigEndian.PutUint16(resp[2:], dnsFlagQR|flags)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(records)))
+
+	for _, rr := range records {
+		resp = append(resp, rr...)
+	}
+
+	return resp
+}
+
+func TestParseResponse(t *testing.T) {
+	answer := record([]byte(questionPointer), dnsTypeA, []byte{192, 0, 2, 1})
+	web := wireName(t, "web.example.test")
+
+	query, err := dnsQuery(1, "example.test", dnsTypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// counts one more answer than there are
+	extra := response(t, dnsTypeA, 0, answer)
+	binary.BigEndian.PutUint16(extra[6:], 2)
+
+	tests := []struct {
+		name  string
+		qtype dnsType
+		msg   []byte
+		want  []string
+		err   error
+	}{
+		{name: "address", qtype: dnsTypeA, msg: response(t, dnsTypeA, 0, answer), want: []string{"192.0.2.1"}},
+		{
+			name:  "cname chain",
+			qtype: dnsTypeA,
+			msg: response(t, dnsTypeA, 0,
+				record([]byte(questionPointer), dnsTypeCNAME, web),
+				record(web, dnsTypeA, []byte{192, 0, 2, 10})),
+			want: []string{"192.0.2.10"},
+		},
+		{name: "no answers", qtype: dnsTypeCNAME, msg: response(t, dnsTypeCNAME, 0)},
+		{name: "short header", qtype: dnsTypeA, msg: response(t, dnsTypeA, 0)[:5], err: ErrMalformedDataError},
+		{name: "query", qtype: dnsTypeA, msg: query, err: ErrMalformedDataError},
+		{name: "servfail", qtype: dnsTypeA, msg: response(t, dnsTypeA, 2), err: ErrDNSQueryError},
+		{name: "cut question", qtype: dnsTypeA, msg: response(t, dnsTypeA, 0)[:dnsHeaderLen+3], err: ErrMalformedDataError},
+		{name: "missing answer", qtype: dnsTypeA, msg: extra, err: ErrMalformedDataError},
+		{name: "cut record header", qtype: dnsTypeA, msg: cut(response(t, dnsTypeA, 0, answer), 8), err: ErrMalformedDataError},
+		{name: "cut record data", qtype: dnsTypeA, msg: cut(response(t, dnsTypeA, 0, answer), 2), err: ErrMalformedDataError},
+		{
+			name:  "looping owner",
+			qtype: dnsTypeA,
+			msg:   response(t, dnsTypeA, 0, record([]byte{0xc0, 30}, dnsTypeA, []byte{192, 0, 2, 1})),
+			err:   ErrMalformedDataError,
+		},
+		{
+			name:  "looping cname",
+			qtype: dnsTypeCNAME,
+			msg:   response(t, dnsTypeCNAME, 0, record([]byte(questionPointer), dnsTypeCNAME, []byte{0xc0, 42})),
+			err:   ErrMalformedDataError,
+		},
+		{
+			name:  "short address",
+			qtype: dnsTypeA,
+			msg:   response(t, dnsTypeA, 0, record([]byte(questionPointer), dnsTypeA, []byte{192, 0, 2})),
+			err:   ErrMalformedDataError,
+		},
+		{
+			name:  "short mx",
+			qtype: dnsTypeMX,
+			msg:   response(t, dnsTypeMX, 0, record([]byte(questionPointer), dnsTypeMX, []byte{0})),
+			err:   ErrMalformedDataError,
+		},
+		{
+			name:  "cut txt",
+			qtype: dnsTypeTXT,
+			msg:   response(t, dnsTypeTXT, 0, record([]byte(questionPointer), dnsTypeTXT, []byte("\x07v=spf1"))),
+			err:   ErrMalformedDataError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponse(tt.msg, tt.qtype)
+			if !errors.Is(err, tt.err) || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseResponse() = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func cut(b []byte, n int) []byte {
+	return b[:len(b)-n]
+}
+
+func FuzzParseResponse(f *testing.F) {
+	web := "\x03web\x07example\x04test\x00"
+
+	f.Add("\x00\x01\x81\x00\x00\x01\x00\x01\x00\x00\x00\x00\x07example\x04test\x00\x00\x01\x00\x01" +
+		"\xc0\x0c\x00\x01\x00\x01\x00\x00\x01\x2c\x00\x04\xc0\x00\x02\x01")
+	f.Add("\x00\x01\x81\x00\x00\x01\x00\x02\x00\x00\x00\x00\x07example\x04test\x00\x00\x0f\x00\x01" +
+		"\xc0\x0c\x00\x0f\x00\x01\x00\x00\x01\x2c\x00\x14\x00\x0a" + web +
+		"\xc0\x0c\x00\x10\x00\x01\x00\x00\x01\x2c\x00\x05\x04-all")
+	f.Add("\x00\x01\x81\x00\x00\x01\x00\x01\x00\x00\x00\x00\xc0\x0c\x00\x05\x00\x01")
+
+	f.Fuzz(func(t *testing.T, msg string) {
+		for _, qtype := range []dnsType{dnsTypeA, dnsTypeCNAME, dnsTypeMX, dnsTypeTXT, dnsTypeAAAA} {
+			answers, err := parseResponse([]byte(msg), qtype)
+			if err != nil && answers != nil {
+				t.Fatalf("parseResponse() = %q with %v", answers, err)
+			}
+		}
+	})
+}
+
+func TestNewDNSCollector(t *testing.T) {
+	c, err := NewDNSCollector(conf.DNS{Resolver: "192.0.2.53", Name: "example.test", Type: "mx", Expect: []string{"Mail.Example.test."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.server != "192.0.2.53:53" || c.qtype != dnsTypeMX || c.expect[0] != "mail.example.test" {
+		t.Errorf("NewDNSCollector() = %+v", c)
+	}
+
+	for _, cfg := range []conf.DNS{
+		{},
+		{Name: "example.test"},
+		{Resolver: "192.0.2.53", Name: "example.test", Type: "SRV"},
+		{Resolver: "192.0.2.53", Name: "bad..example.test"},
+	} {
+		if _, err := NewDNSCollector(cfg); !errors.Is(err, ErrInvalidConfigError) {
+			t.Errorf("NewDNSCollector(%+v) error = %v, want %v", cfg, err, ErrInvalidConfigError)
+		}
+	}
+}
@@ -3,9 +3,22 @@ package monitor
 import "errors"
 
 var (
-	ErrUnknownKeyError              = errors.New("unknown key")
-	ErrUnsupportedMetricMethodError = errors.New("unsupported metric method")
-	ErrIntegerOutOfRange            = errors.New("integer out of range of uint64")
-	ErrInvalidTimeError             = errors.New("invalid time")
-	ErrUnknownValueType             = errors.New("unknown value type")
+	ErrUnknownKeyError         = errors.New("unknown key")
+	ErrIntegerOutOfRange       = errors.New("integer out of range of int")
+	ErrInvalidTimeError        = errors.New("invalid time")
+	ErrUnknownValueType        = errors.New("unknown value type")
+	ErrUnknownDeviceError      = errors.New("unknown device")
+	ErrMalformedDataError      = errors.New("malformed data")
+	ErrInvalidConfigError      = errors.New("invalid config")
+	ErrCommandFailedError      = errors.New("command failed")
+	ErrTimeoutError            = errors.New("timed out")
+	ErrNoCertificateError      = errors.New("no certificate found")
+	ErrDNSQueryError           = errors.New("DNS query failed")
+	ErrUnexpectedStatusError   = errors.New("unexpected status")
+	ErrUnknownAggregationError = errors.New("unknown aggregation")
+	ErrInvalidStepError        = errors.New("invalid step")
+	ErrPluginDownError         = errors.New("plugin is down")
+	ErrPluginExitedError       = errors.New("plugin exited")
+	ErrPluginFailedError       = errors.New("plugin failed")
+	ErrInvalidSelectorError    = errors.New("invalid selector")
 )
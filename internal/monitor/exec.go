@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const (
+	defaultExecTimeout = 10 * time.Second
+	execWaitDelay      = time.Second
+	maxStderrLen       = 256
+)
+
+// ExecCollector runs an external command and turns its output
+// into metric values.
+type ExecCollector struct {
+	command  []string
+	timeout  time.Duration
+	keyValue bool
+	valType  string
+}
+
+func parseValue(raw string, t string) ([]byte, error) {
+	switch t {
+	case "string":
+		return []byte(raw), nil
+	case "int":
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedDataError, err)
+		}
+
+		return IntToBytes(i)
+	case "float":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedDataError, err)
+		}
+
+		return Float64ToBytes(f)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownValueType, t)
+}
+
+func (c *ExecCollector) run(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = execWaitDelay
+
+	err := cmd.Run()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: %s after %s", ErrTimeoutError, c.command[0], c.timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > maxStderrLen {
+			msg = msg[:maxStderrLen]
+		}
+
+		return nil, fmt.Errorf("%w: %s: exit code %d: %s",
+			ErrCommandFailedError, c.command[0], exitErr.ExitCode(), msg)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrCommandFailedError, c.command[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (c *ExecCollector) parseKeyValue(out []byte) ([]Value, error) {
+	var values []Value
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// the value is the rest of the line, strings may have spaces
+		name := strings.Fields(line)[0]
+
+		raw := strings.TrimSpace(strings.TrimPrefix(line, name))
+		if raw == "" {
+			return nil, fmt.Errorf("%w: %q", ErrMalformedDataError, line)
+		}
+
+		b, err := parseValue(raw, c.valType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		values = append(values, Value{Name: name, Data: b})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan output: %w", err)
+	}
+
+	return values, nil
+}
+
+func (c *ExecCollector) Collect(ctx context.Context, m *Metric) error {
+	out, err := c.run(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.keyValue {
+		values, err := c.parseKeyValue(out)
+		if err != nil {
+			return err
+		}
+
+		m.LastValue = values
+
+		return nil
+	}
+
+	b, err := parseValue(strings.TrimSpace(string(out)), c.valType)
+	if err != nil {
+		return err
+	}
+
+	m.LastValue = []Value{{Data: b}}
+
+	return nil
+}
+
+func NewExecCollector(cfg conf.Exec, valType string) (*ExecCollector, error) {
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("%w: command is required", ErrInvalidConfigError)
+	}
+
+	if _, err := parseValue("0", valType); err != nil {
+		return nil, fmt.Errorf("%w: type: %w", ErrInvalidConfigError, err)
+	}
+
+	c := &ExecCollector{command: cfg.Command, timeout: defaultExecTimeout, valType: valType}
+
+	if cfg.Timeout > 0 {
+		c.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	switch cfg.Output {
+	case "", "value":
+	case "keyvalue":
+		c.keyValue = true
+	default:
+		return nil, fmt.Errorf("%w: unknown output mode %q", ErrInvalidConfigError, cfg.Output)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+func decoded(t *testing.T, v Value, typ string) any {
+	t.Helper()
+
+	d, err := fromBytes(v.Data, typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		typ  string
+		want any
+	}{
+		{raw: "42", typ: "int", want: 42},
+		{raw: "-17", typ: "int", want: -17},
+		{raw: "-0.25", typ: "float", want: -0.25},
+		{raw: "up and running", typ: "string", want: "up and running"},
+	}
+
+	for _, tt := range tests {
+		b, err := parseValue(tt.raw, tt.typ)
+		if err != nil {
+			t.Fatalf("parseValue(%q, %s): %v", tt.raw, tt.typ, err)
+		}
+
+		if got := decoded(t, Value{Data: b}, tt.typ); got != tt.want {
+			t.Errorf("parseValue(%q, %s) = %v, want %v", tt.raw, tt.typ, got, tt.want)
+		}
+	}
+
+	if _, err := parseValue("1.5", "int"); !errors.Is(err, ErrMalformedDataError) {
+		t.Errorf("parseValue(1.5, int) error = %v, want %v", err, ErrMalformedDataError)
+	}
+
+	if _, err := parseValue("1", "bool"); !errors.Is(err, ErrUnknownValueType) {
+		t.Errorf("parseValue(1, bool) error = %v, want %v", err, ErrUnknownValueType)
+	}
+}
+
+func TestParseKeyValue(t *testing.T) {
+	c := &ExecCollector{valType: "int"}
+
+	out := "# comment\nqueued 3\nactive\t\t-2\n  failed   7  \n\n"
+
+	values, err := c.parseKeyValue([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"queued": 3, "active": -2, "failed": 7}
+	if len(values) != len(want) {
+		t.Fatalf("parseKeyValue() returned %d values, want %d", len(values), len(want))
+	}
+
+	for _, v := range values {
+		if got := decoded(t, v, "int"); got != want[v.Name] {
+			t.Errorf("%s = %v, want %v", v.Name, got, want[v.Name])
+		}
+	}
+
+	for _, bad := range []string{"lonely\n", "queued three\n"} {
+		if _, err := c.parseKeyValue([]byte(bad)); !errors.Is(err, ErrMalformedDataError) {
+			t.Errorf("parseKeyValue(%q) error = %v, want %v", bad, err, ErrMalformedDataError)
+		}
+	}
+
+	c.valType = "string"
+
+	values, err = c.parseKeyValue([]byte("status\tall good\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := decoded(t, values[0], "string"); got != "all good" {
+		t.Errorf("status = %q, want %q", got, "all good")
+	}
+}
+
+func TestExecCollect(t *testing.T) {
+	c, err := NewExecCollector(conf.Exec{Command: []string{"sh", "-c", "echo -5"}}, "int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m Metric
+	if err := c.Collect(context.Background(), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := decoded(t, m.LastValue[0], "int"); got != -5 {
+		t.Errorf("Collect() = %v, want -5", got)
+	}
+
+	c, err = NewExecCollector(conf.Exec{Command: []string{"sh", "-c", "echo oops >&2; exit 3"}}, "int")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Collect(context.Background(), &m); !errors.Is(err, ErrCommandFailedError) {
+		t.Errorf("Collect() error = %v, want %v", err, ErrCommandFailedError)
+	}
+}
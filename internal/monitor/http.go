@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	log "log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const (
+	defaultProbeTimeout = 10 * time.Second
+	maxBodySize         = 1 << 20
+	hoursPerDay         = 24
+)
+
+// HTTPCollector probes an HTTP endpoint and reports its availability,
+// status code, latency, certificate expiry and body assertion result.
+type HTTPCollector struct {
+	client         *http.Client
+	url            string
+	method         string
+	headers        map[string]string
+	body           string
+	expectedStatus []int
+	match          *regexp.Regexp
+	contains       string
+}
+
+func (c *HTTPCollector) statusUp(code int) bool {
+	if len(c.expectedStatus) == 0 {
+		return code >= http.StatusOK && code < http.StatusBadRequest
+	}
+
+	return slices.Contains(c.expectedStatus, code)
+}
+
+func (c *HTTPCollector) checkBody(body []byte) bool {
+	if c.match != nil && !c.match.Match(body) {
+		return false
+	}
+
+	if c.contains != "" && !strings.Contains(string(body), c.contains) {
+		return false
+	}
+
+	return true
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func daysUntil(t time.Time) float64 {
+	return time.Until(t).Hours() / hoursPerDay
+}
+
+func (c *HTTPCollector) Collect(ctx context.Context, m *Metric) error {
+	req, err := http.NewRequestWithContext(ctx, c.method, c.url, strings.NewReader(c.body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.DebugContext(ctx, "HTTP probe failed", log.String("url", c.url), log.Any("error", err))
+
+		m.LastValue = []Value{boolValue("up", false)}
+
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	latency := time.Since(start)
+
+	if err != nil {
+		m.LastValue = []Value{boolValue("up", false), intValue("status_code", resp.StatusCode)}
+
+		return nil
+	}
+
+	values := []Value{
+		boolValue("up", c.statusUp(resp.StatusCode)),
+		intValue("status_code", resp.StatusCode),
+		floatValue("latency_ms", millis(latency)),
+	}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		values = append(values, floatValue("tls_expiry_days", daysUntil(resp.TLS.PeerCertificates[0].NotAfter)))
+	}
+
+	if c.match != nil || c.contains != "" {
+		values = append(values, boolValue("match", c.checkBody(body)))
+	}
+
+	m.LastValue = values
+
+	return nil
+}
+
+func NewHTTPCollector(cfg conf.HTTP) (*HTTPCollector, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidConfigError)
+	}
+
+	timeout := defaultProbeTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	transport := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify},
+		DisableKeepAlives: true,
+	}
+
+	c := &HTTPCollector{
+		client:         &http.Client{Timeout: timeout, Transport: transport},
+		url:            cfg.URL,
+		method:         http.MethodGet,
+		headers:        cfg.Headers,
+		body:           cfg.Body,
+		expectedStatus: cfg.ExpectedStatus,
+		contains:       cfg.Contains,
+	}
+
+	if cfg.Method != "" {
+		c.method = strings.ToUpper(cfg.Method)
+	}
+
+	if cfg.Match != "" {
+		re, err := regexp.Compile(cfg.Match)
+		if err != nil {
+			return nil, fmt.Errorf("%w: match: %w", ErrInvalidConfigError, err)
+		}
+
+		c.match = re
+	}
+
+	return c, nil
+}
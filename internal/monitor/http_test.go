@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// collectValues collects the metric once and returns
+// its values decoded by name.
+func collectValues(t *testing.T, collect func(context.Context, *Metric) error) map[string]any {
+	t.Helper()
+
+	var m Metric
+	if err := collect(context.Background(), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]any, len(m.LastValue))
+	for _, v := range m.LastValue {
+		values[v.Name] = decoded(t, v, v.Type)
+	}
+
+	return values
+}
+
+func newHTTPCollector(t *testing.T, cfg conf.HTTP) *HTTPCollector {
+	t.Helper()
+
+	c, err := NewHTTPCollector(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestHTTPCollect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.Method != http.MethodPost || r.Header.Get("X-Probe") != "1" || string(body) != "ping":
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		default:
+			_, _ = io.WriteString(w, `{"status": "ok"}`)
+		}
+	}))
+	defer srv.Close()
+
+	values := collectValues(t, newHTTPCollector(t, conf.HTTP{
+		URL:      srv.URL,
+		Method:   "post",
+		Headers:  map[string]string{"X-Probe": "1"},
+		Body:     "ping",
+		Match:    `"status":\s*"ok"`,
+		Contains: "ok",
+	}).Collect)
+
+	if values["up"] != 1 || values["status_code"] != http.StatusOK || values["match"] != 1 {
+		t.Errorf("Collect() = %v, want up, 200 and a match", values)
+	}
+
+	if latency, ok := values["latency_ms"].(float64); !ok || latency <= 0 {
+		t.Errorf("latency_ms = %v, want a positive latency", values["latency_ms"])
+	}
+
+	values = collectValues(t, newHTTPCollector(t, conf.HTTP{URL: srv.URL, Contains: "degraded"}).Collect)
+	if values["up"] != 0 || values["status_code"] != http.StatusBadRequest || values["match"] != 0 {
+		t.Errorf("Collect() = %v, want down, 400 and no match", values)
+	}
+
+	values = collectValues(t, newHTTPCollector(t, conf.HTTP{
+		URL:            srv.URL + "/missing",
+		ExpectedStatus: []int{http.StatusNotFound},
+	}).Collect)
+	if values["up"] != 1 || values["status_code"] != http.StatusNotFound {
+		t.Errorf("Collect() = %v, want up with the expected 404", values)
+	}
+}
+
+func TestHTTPCollectTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	values := collectValues(t, newHTTPCollector(t, conf.HTTP{URL: srv.URL, InsecureSkipVerify: true}).Collect)
+	if days, ok := values["tls_expiry_days"].(float64); !ok || days <= 0 {
+		t.Errorf("tls_expiry_days = %v, want a certificate valid for days", values["tls_expiry_days"])
+	}
+
+	// the test certificate is not trusted
+	values = collectValues(t, newHTTPCollector(t, conf.HTTP{URL: srv.URL}).Collect)
+	if values["up"] != 0 || len(values) != 1 {
+		t.Errorf("Collect() = %v, want only down", values)
+	}
+}
+
+func TestHTTPCollectDown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Close()
+
+	values := collectValues(t, newHTTPCollector(t, conf.HTTP{URL: srv.URL}).Collect)
+	if values["up"] != 0 || len(values) != 1 {
+		t.Errorf("Collect() = %v, want only down", values)
+	}
+}
@@ -0,0 +1,324 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const indexLabel = "index"
+
+// Labels are the dimensions of a series, like the mountpoint
+// of a disk or the index of a CPU thread.
+type Labels map[string]string
+
+// String returns the labels in the `{name="value",...}` form ordered
+// by name, or an empty string if there are none. It identifies the
+// labels in storage.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(l[name])
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// With returns the labels merged with other, other takes precedence.
+func (l Labels) With(other Labels) Labels {
+	if len(other) == 0 {
+		return l
+	}
+
+	if len(l) == 0 {
+		return other
+	}
+
+	merged := make(Labels, len(l)+len(other))
+
+	for name, v := range l {
+		merged[name] = v
+	}
+
+	for name, v := range other {
+		merged[name] = v
+	}
+
+	return merged
+}
+
+// ParseLabels parses labels in the form returned by Labels.String.
+func ParseLabels(s string) (Labels, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	matchers, rest, err := cutMatchers(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if rest != "" {
+		return nil, fmt.Errorf("%w: trailing %q", ErrInvalidSelectorError, rest)
+	}
+
+	labels := make(Labels, len(matchers))
+
+	for _, m := range matchers {
+		if m.Op != "=" {
+			return nil, fmt.Errorf("%w: %s is not a label", ErrInvalidSelectorError, m.Op)
+		}
+
+		labels[m.Name] = m.Value
+	}
+
+	return labels, nil
+}
+
+// labelCache parses stored labels, every distinct string once.
+type labelCache map[string]Labels
+
+func (c labelCache) parse(s string) (Labels, error) {
+	if l, ok := c[s]; ok {
+		return l, nil
+	}
+
+	l, err := ParseLabels(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c[s] = l
+
+	return l, nil
+}
+
+// Matcher matches a label against a value with =, !=,
+// or a regular expression with =~ and !~. A missing
+// label matches as an empty value.
+type Matcher struct {
+	Name  string
+	Op    string
+	Value string
+	re    *regexp.Regexp
+}
+
+func (m Matcher) Matches(labels Labels) bool {
+	v := labels[m.Name]
+
+	switch m.Op {
+	case "=":
+		return v == m.Value
+	case "!=":
+		return v != m.Value
+	case "=~":
+		return m.re.MatchString(v)
+	case "!~":
+		return !m.re.MatchString(v)
+	}
+
+	return false
+}
+
+// Selector selects series by key and label matchers.
+type Selector struct {
+	Key      string
+	Matchers []Matcher
+}
+
+// MatchLabels reports whether the labels satisfy every matcher.
+func (s Selector) MatchLabels(labels Labels) bool {
+	for _, m := range s.Matchers {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches reports whether the series is selected. Unless strict,
+// the key of the selector is a prefix.
+func (s Selector) Matches(key string, labels Labels, strict bool) bool {
+	if strict {
+		return key == s.Key && s.MatchLabels(labels)
+	}
+
+	return strings.HasPrefix(key, s.Key) && s.MatchLabels(labels)
+}
+
+// ParseSelector parses `<key>{<label><op>"<value>",...}`,
+// both the key and the matchers are optional.
+func ParseSelector(s string) (Selector, error) {
+	sel, rest, err := CutSelector(s)
+	if err != nil {
+		return Selector{}, err
+	}
+
+	if rest != "" {
+		return Selector{}, fmt.Errorf("%w: trailing %q", ErrInvalidSelectorError, rest)
+	}
+
+	return sel, nil
+}
+
+// CutSelector parses the selector at the start of s and returns
+// it with the rest of s.
+func CutSelector(s string) (Selector, string, error) {
+	s = strings.TrimSpace(s)
+
+	end := strings.IndexFunc(s, func(r rune) bool { return r == '{' || r == ' ' || r == '\t' })
+	if end < 0 {
+		return Selector{Key: s}, "", nil
+	}
+
+	sel := Selector{Key: s[:end]}
+	rest := strings.TrimSpace(s[end:])
+
+	if !strings.HasPrefix(rest, "{") {
+		return sel, rest, nil
+	}
+
+	matchers, rest, err := cutMatchers(rest)
+	if err != nil {
+		return Selector{}, "", err
+	}
+
+	sel.Matchers = matchers
+
+	return sel, rest, nil
+}
+
+func isLabelName(s string) bool {
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+// cutMatchers parses `{...}` at the start of s.
+func cutMatchers(s string) ([]Matcher, string, error) {
+	rest, ok := strings.CutPrefix(s, "{")
+	if !ok {
+		return nil, "", fmt.Errorf("%w: expected {", ErrInvalidSelectorError)
+	}
+
+	var matchers []Matcher
+
+	for {
+		rest = strings.TrimSpace(rest)
+
+		if after, ok := strings.CutPrefix(rest, "}"); ok {
+			return matchers, strings.TrimSpace(after), nil
+		}
+
+		if len(matchers) > 0 {
+			after, ok := strings.CutPrefix(rest, ",")
+			if !ok {
+				return nil, "", fmt.Errorf("%w: expected , or }", ErrInvalidSelectorError)
+			}
+
+			rest = strings.TrimSpace(after)
+		}
+
+		m, after, err := cutMatcher(rest)
+		if err != nil {
+			return nil, "", err
+		}
+
+		matchers = append(matchers, m)
+		rest = after
+	}
+}
+
+func cutMatcher(s string) (Matcher, string, error) {
+	i := strings.IndexAny(s, "=!")
+	if i < 0 {
+		return Matcher{}, "", fmt.Errorf("%w: expected matcher in %q", ErrInvalidSelectorError, s)
+	}
+
+	m := Matcher{Name: strings.TrimSpace(s[:i])}
+	if !isLabelName(m.Name) {
+		return Matcher{}, "", fmt.Errorf("%w: label name %q", ErrInvalidSelectorError, m.Name)
+	}
+
+	rest := s[i:]
+
+	for _, op := range []string{"=~", "!~", "!=", "="} {
+		if after, ok := strings.CutPrefix(rest, op); ok {
+			m.Op = op
+			rest = strings.TrimSpace(after)
+
+			break
+		}
+	}
+
+	if m.Op == "" {
+		return Matcher{}, "", fmt.Errorf("%w: operator of %s", ErrInvalidSelectorError, m.Name)
+	}
+
+	quoted, err := strconv.QuotedPrefix(rest)
+	if err != nil {
+		return Matcher{}, "", fmt.Errorf("%w: value of %s", ErrInvalidSelectorError, m.Name)
+	}
+
+	m.Value, err = strconv.Unquote(quoted)
+	if err != nil {
+		return Matcher{}, "", fmt.Errorf("%w: value of %s", ErrInvalidSelectorError, m.Name)
+	}
+
+	if m.Op == "=~" || m.Op == "!~" {
+		m.re, err = regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return Matcher{}, "", fmt.Errorf("%w: %w", ErrInvalidSelectorError, err)
+		}
+	}
+
+	return m, rest[len(quoted):], nil
+}
+
+// objectFamily is a key prefix followed by a stat name and an object
+// (mountpoint, device, interface) collected with it as label.
+type objectFamily struct {
+	prefix string
+	label  string
+}
+
+func objectFamilies() []objectFamily {
+	return []objectFamily{
+		{"disk.io.", "device"},
+		{"disk.inodes.", "mountpoint"},
+		{"disk.", "mountpoint"},
+		{"net.", "interface"},
+	}
+}
+
+// objectDesc returns the key values of the internal key are stored
+// under and its labels, the object of disk and net keys is a label.
+func objectDesc(key string) (string, Labels) {
+	for _, f := range objectFamilies() {
+		if stat, object, ok := splitKey(key, f.prefix); ok {
+			return f.prefix + stat, Labels{f.label: object}
+		}
+	}
+
+	return key, nil
+}
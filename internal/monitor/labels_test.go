@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// matcherStrings returns the matchers in their source form.
+func matcherStrings(matchers []Matcher) []string {
+	var s []string
+	for _, m := range matchers {
+		s = append(s, m.Name+m.Op+strconv.Quote(m.Value))
+	}
+
+	return s
+}
+
+func TestLabelsString(t *testing.T) {
+	tests := []struct {
+		labels Labels
+		want   string
+	}{
+		{labels: nil, want: ""},
+		{labels: Labels{}, want: ""},
+		{labels: Labels{"index": "3"}, want: `{index="3"}`},
+		{labels: Labels{"mountpoint": "/var", "device": "sda1"}, want: `{device="sda1",mountpoint="/var"}`},
+		{labels: Labels{"name": `say "hi"`}, want: `{name="say \"hi\""}`},
+		{labels: Labels{"path": `C:\temp`, "line": "a\nb"}, want: `{line="a\nb",path="C:\\temp"}`},
+		{labels: Labels{"empty": ""}, want: `{empty=""}`},
+	}
+
+	for _, tt := range tests {
+		got := tt.labels.String()
+		if got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+
+		parsed, err := ParseLabels(got)
+		if err != nil {
+			t.Errorf("ParseLabels(%s) error = %v", got, err)
+		}
+
+		if len(tt.labels) > 0 && !reflect.DeepEqual(parsed, tt.labels) {
+			t.Errorf("ParseLabels(%s) = %v, want %v", got, parsed, tt.labels)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Labels
+		wantErr bool
+	}{
+		{s: "", want: nil},
+		{s: "{}", want: Labels{}},
+		{s: `{ index = "0" , cpu="cpu0" }`, want: Labels{"index": "0", "cpu": "cpu0"}},
+		{s: `{name="a,b}"}`, want: Labels{"name": "a,b}"}},
+		{s: `{index!="0"}`, wantErr: true},
+		{s: `{index=~"0"}`, wantErr: true},
+		{s: `{index="0"}x`, wantErr: true},
+		{s: `index="0"`, wantErr: true},
+		{s: `{index="0"`, wantErr: true},
+		{s: `{index=0}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLabels(tt.s)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSelectorError) {
+				t.Errorf("ParseLabels(%s) error = %v, want %v", tt.s, err, ErrInvalidSelectorError)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLabels(%s) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestCutMatchers(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		rest    string
+		wantErr bool
+	}{
+		{s: "{}", want: nil},
+		{s: "{ } > 90", want: nil, rest: "> 90"},
+		{s: `{index="0"}`, want: []string{`index="0"`}},
+		{s: `{a!="x", b=~"y.*",c!~""} for 2m`, want: []string{`a!="x"`, `b=~"y.*"`, `c!~""`}, rest: "for 2m"},
+		{s: `{name="say \"hi\""}`, want: []string{`name="say \"hi\""`}},
+		{s: `{name="}"}`, want: []string{`name="}"`}},
+		{s: `index="0"}`, wantErr: true},
+		{s: "{", wantErr: true},
+		{s: "{,}", wantErr: true},
+		{s: `{a="x",}`, wantErr: true},
+		{s: `{a="x" b="y"}`, wantErr: true},
+		{s: `{a="x"`, wantErr: true},
+		{s: `{a}`, wantErr: true},
+		{s: `{="x"}`, wantErr: true},
+		{s: `{1a="x"}`, wantErr: true},
+		{s: `{a-b="x"}`, wantErr: true},
+		{s: `{a=="x"}`, wantErr: true},
+		{s: `{a=x}`, wantErr: true},
+		{s: `{a="x}`, wantErr: true},
+		{s: `{a=~"("}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, rest, err := cutMatchers(tt.s)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSelectorError) {
+				t.Errorf("cutMatchers(%s) error = %v, want %v", tt.s, err, ErrInvalidSelectorError)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(matcherStrings(got), tt.want) || rest != tt.rest {
+			t.Errorf("cutMatchers(%s) = %v, %q, %v, want %v, %q", tt.s, matcherStrings(got), rest, err, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		s        string
+		key      string
+		matchers []string
+		wantErr  bool
+	}{
+		{s: "", key: ""},
+		{s: "cpu.percent", key: "cpu.percent"},
+		{s: " cpu.percent ", key: "cpu.percent"},
+		{s: "cpu.percent{}", key: "cpu.percent"},
+		{s: `cpu.percent.thread{index!="0"}`, key: "cpu.percent.thread", matchers: []string{`index!="0"`}},
+		{s: `cpu.percent.thread {index=~"1|2"}`, key: "cpu.percent.thread", matchers: []string{`index=~"1|2"`}},
+		{s: `{mountpoint="/"}`, key: "", matchers: []string{`mountpoint="/"`}},
+		{s: "cpu.percent > 90", wantErr: true},
+		{s: `cpu.percent{index="0"} x`, wantErr: true},
+		{s: `cpu.percent{index="0"`, wantErr: true},
+		{s: `cpu.percent{index=0}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSelector(tt.s)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSelectorError) {
+				t.Errorf("ParseSelector(%s) error = %v, want %v", tt.s, err, ErrInvalidSelectorError)
+			}
+
+			continue
+		}
+
+		if err != nil || got.Key != tt.key || !reflect.DeepEqual(matcherStrings(got.Matchers), tt.matchers) {
+			t.Errorf("ParseSelector(%s) = %s %v, %v", tt.s, got.Key, matcherStrings(got.Matchers), err)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	tests := []struct {
+		sel    string
+		key    string
+		labels Labels
+		strict bool
+		want   bool
+	}{
+		{sel: "cpu.percent", key: "cpu.percent.thread", labels: Labels{"index": "0"}, want: true},
+		{sel: "cpu.percent", key: "cpu.percent.thread", strict: true, want: false},
+		{sel: `cpu.percent.thread{index="0"}`, key: "cpu.percent.thread", labels: Labels{"index": "0"}, want: true},
+		{sel: `cpu.percent.thread{index!="0"}`, key: "cpu.percent.thread", labels: Labels{"index": "0"}, want: false},
+		{sel: `cpu.percent.thread{index!="0"}`, key: "cpu.percent.thread", labels: Labels{"index": "1"}, want: true},
+		{sel: `cpu.percent.thread{index=~"1"}`, key: "cpu.percent.thread", labels: Labels{"index": "11"}, want: false},
+		{sel: `cpu.percent.thread{index!~"1.*"}`, key: "cpu.percent.thread", labels: Labels{"index": "11"}, want: false},
+		{sel: `disk.percent{mountpoint=""}`, key: "disk.percent", want: true},
+		{sel: `disk.percent{mountpoint!=""}`, key: "disk.percent", want: false},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := sel.Matches(tt.key, tt.labels, tt.strict); got != tt.want {
+			t.Errorf("%s Matches(%s%s, %t) = %t, want %t", tt.sel, tt.key, tt.labels, tt.strict, got, tt.want)
+		}
+	}
+}
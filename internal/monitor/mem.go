@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/mem"
+)
+
+func MemByKey(ctx context.Context, key string) ([][]byte, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory stats: %w", err)
+	}
+
+	var b []byte
+
+	switch key {
+	case "mem.total":
+		b, err = Uint64ToBytes(vm.Total)
+	case "mem.used":
+		b, err = Uint64ToBytes(vm.Used)
+	case "mem.available":
+		b, err = Uint64ToBytes(vm.Available)
+	case "mem.percent":
+		b, err = Float64ToBytes(vm.UsedPercent)
+	case "mem.cached":
+		b, err = Uint64ToBytes(vm.Cached)
+	case "mem.buffers":
+		b, err = Uint64ToBytes(vm.Buffers)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert: %w", err)
+	}
+
+	return [][]byte{b}, nil
+}
+
+func SwapByKey(ctx context.Context, key string) ([][]byte, error) {
+	sm, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap stats: %w", err)
+	}
+
+	var b []byte
+
+	switch key {
+	case "swap.total":
+		b, err = Uint64ToBytes(sm.Total)
+	case "swap.used":
+		b, err = Uint64ToBytes(sm.Used)
+	case "swap.free":
+		b, err = Uint64ToBytes(sm.Free)
+	case "swap.percent":
+		b, err = Float64ToBytes(sm.UsedPercent)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert: %w", err)
+	}
+
+	return [][]byte{b}, nil
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	log "log/slog"
+	"strconv"
+	"strings"
+)
+
+// legacySeries returns the series a key stored before labels belongs
+// to, the object of disk and net keys and indices of internal keys
+// were appended to the key. It reports false if no configured metric
+// collected the key.
+func (s *Service) legacySeries(key string) (string, Labels, bool) {
+	var metric *Metric
+
+	for _, m := range s.metrics {
+		if key != m.Key && !strings.HasPrefix(key, m.Key+".") {
+			continue
+		}
+
+		if metric == nil || len(m.Key) > len(metric.Key) {
+			metric = m
+		}
+	}
+
+	if metric == nil {
+		return "", nil, false
+	}
+
+	series, labels := metric.Series, metric.Labels
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(key, metric.Key), ".")
+	if rest == "" {
+		return series, labels, true
+	}
+
+	if _, err := strconv.Atoi(rest); err == nil && metric.Method == "internal" {
+		return series, labels.With(Labels{indexLabel: rest}), true
+	}
+
+	return series + "." + rest, labels, true
+}
+
+// legacyKey returns the key the series was stored under before labels,
+// with the index or the object of disk and net keys appended.
+func legacyKey(key string, labels Labels) string {
+	if index, ok := labels[indexLabel]; ok {
+		return key + "." + index
+	}
+
+	for _, f := range objectFamilies() {
+		if object, ok := labels[f.label]; ok && strings.HasPrefix(key, f.prefix) {
+			return key + "." + object
+		}
+	}
+
+	return key
+}
+
+// MigrateKeys moves values stored under keys with the object or the
+// index of the value appended to the labelled series of the
+// configured metrics. It is a no-op once every key is moved.
+func (s *Service) MigrateKeys(ctx context.Context) error {
+	keys, err := s.repo.UnlabeledKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate keys: %w", err)
+	}
+
+	for _, key := range keys {
+		series, labels, ok := s.legacySeries(key)
+		if !ok || series == key && len(labels) == 0 {
+			continue
+		}
+
+		if err := s.repo.Relabel(ctx, key, series, labels.String()); err != nil {
+			return fmt.Errorf("failed to migrate keys: %w", err)
+		}
+
+		log.InfoContext(ctx, "migrated key", log.String("key", key), log.String("series", series+labels.String()))
+	}
+
+	return nil
+}
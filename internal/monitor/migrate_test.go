@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// internalMetric returns a built-in metric with the series
+// and labels of its key.
+func internalMetric(key string) *Metric {
+	series, labels := objectDesc(key)
+
+	return &Metric{Key: key, Method: "internal", Series: series, Labels: labels}
+}
+
+func TestMigrateKeys(t *testing.T) {
+	s, conn := newTestService(t)
+	s.metrics = []*Metric{
+		internalMetric("cpu.percent"),
+		internalMetric("cpu.percent.thread"),
+		internalMetric("disk.percent./var/lib"),
+		internalMetric("disk.io.read_bytes.sda1"),
+		internalMetric("net.bytes_recv.eth0.100"),
+		{Key: "app.queue", Method: "exec", Series: "app.queue"},
+	}
+
+	execAll(t, conn,
+		`INSERT INTO series (id, key, type) VALUES
+			(1, 'cpu.percent', 'real'),
+			(2, 'cpu.percent.thread.0', 'real'),
+			(3, 'cpu.percent.thread.11', 'real'),
+			(4, 'disk.percent./var/lib', 'real'),
+			(5, 'disk.io.read_bytes.sda1', 'int'),
+			(6, 'net.bytes_recv.eth0.100', 'int'),
+			(7, 'app.queue.3', 'int'),
+			(8, 'load.1', 'real')`,
+		`INSERT INTO metric (series_id, int_value, date) VALUES
+			(1, 1, '2024-01-01 00:00:00'), (2, 2, '2024-01-01 00:00:00'), (3, 3, '2024-01-01 00:00:00'),
+			(4, 4, '2024-01-01 00:00:00'), (5, 5, '2024-01-01 00:00:00'), (6, 6, '2024-01-01 00:00:00'),
+			(7, 7, '2024-01-01 00:00:00'), (8, 8, '2024-01-01 00:00:00')`)
+
+	want := map[string]int64{
+		"cpu.percent":                          1,
+		`cpu.percent.thread{index="0"}`:        2,
+		`cpu.percent.thread{index="11"}`:       3,
+		`disk.percent{mountpoint="/var/lib"}`:  4,
+		`disk.io.read_bytes{device="sda1"}`:    5,
+		`net.bytes_recv{interface="eth0.100"}`: 6,
+		"app.queue.3":                          7,
+		"load.1":                               8,
+	}
+
+	// the second run finds nothing left to move
+	for range 2 {
+		if err := s.MigrateKeys(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := conn.Query(`SELECT series.key || series.labels, metric.int_value
+			FROM metric JOIN series ON series.id = metric.series_id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make(map[string]int64)
+
+		for rows.Next() {
+			var (
+				series string
+				v      int64
+			)
+
+			if err := rows.Scan(&series, &v); err != nil {
+				t.Fatal(err)
+			}
+
+			got[series] = v
+		}
+
+		rows.Close()
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	if n := count(t, conn, `SELECT COUNT(*) FROM series`); n != len(want) {
+		t.Errorf("%d series left, want %d", n, len(want))
+	}
+}
+
+func TestLegacyKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		labels Labels
+		want   string
+	}{
+		{key: "cpu.percent", want: "cpu.percent"},
+		{key: "cpu.percent.thread", labels: Labels{indexLabel: "3"}, want: "cpu.percent.thread.3"},
+		{key: "disk.percent", labels: Labels{"mountpoint": "/"}, want: "disk.percent./"},
+		{key: "disk.io.read_bytes", labels: Labels{"device": "sda1"}, want: "disk.io.read_bytes.sda1"},
+		{key: "net.bytes_recv", labels: Labels{"interface": "eth0"}, want: "net.bytes_recv.eth0"},
+		{key: "app.queue", labels: Labels{"interface": "eth0"}, want: "app.queue"},
+	}
+
+	for _, tt := range tests {
+		if got := legacyKey(tt.key, tt.labels); got != tt.want {
+			t.Errorf("legacyKey(%s%s) = %s, want %s", tt.key, tt.labels, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/net"
+)
+
+// maxWrapDelta is the largest delta accepted as a 32-bit counter wrap.
+// A bigger one means the counter was reset (interface re-created,
+// driver reloaded), so no rate is reported for that sample.
+const maxWrapDelta = math.MaxUint32 / 2
+
+// counterBits is the width of interface counters. Drivers without 64-bit
+// stats count in the unsigned long of the kernel, assumed to be as wide
+// as the int of the build.
+const counterBits = strconv.IntSize
+
+func netCounter(stat string, c psnet.IOCountersStat) (uint64, error) {
+	switch stat {
+	case "rx_bytes":
+		return c.BytesRecv, nil
+	case "tx_bytes":
+		return c.BytesSent, nil
+	case "rx_packets":
+		return c.PacketsRecv, nil
+	case "tx_packets":
+		return c.PacketsSent, nil
+	case "rx_errors":
+		return c.Errin, nil
+	case "tx_errors":
+		return c.Errout, nil
+	case "rx_dropped":
+		return c.Dropin, nil
+	case "tx_dropped":
+		return c.Dropout, nil
+	}
+
+	return 0, fmt.Errorf("%w: net.%s", ErrUnknownKeyError, stat)
+}
+
+// counterDelta returns the increase of a monotonic counter of bits
+// width between two samples. A decrease is taken for a wrap only for
+// 32-bit counters, it reports false if the counter was reset.
+func counterDelta(prev uint64, cur uint64, bits int) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+
+	if bits != 32 || prev > math.MaxUint32 {
+		return 0, false
+	}
+
+	delta := cur + (math.MaxUint32 - prev) + 1
+	if delta > maxWrapDelta {
+		return 0, false
+	}
+
+	return delta, true
+}
+
+// NetByKey handles keys in the form net.<stat>.<interface>. Besides
+// the raw counter it returns a per-second rate named "rate", computed
+// from the previous counter value in prev collected elapsed ago.
+func NetByKey(ctx context.Context, key string, prev []Value, elapsed time.Duration) ([]Value, error) {
+	stat, iface, ok := splitKey(key, "net.")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	counters, err := psnet.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network counters: %w", err)
+	}
+
+	var (
+		cur   uint64
+		found bool
+	)
+
+	for _, c := range counters {
+		if c.Name != iface {
+			continue
+		}
+
+		cur, err = netCounter(stat, c)
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+
+		break
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDeviceError, iface)
+	}
+
+	b, err := Uint64ToBytes(cur)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert: %w", err)
+	}
+
+	values := []Value{{Type: "int", Data: b}}
+
+	if len(prev) == 0 || prev[0].Name != "" || elapsed <= 0 {
+		return values, nil
+	}
+
+	delta, ok := counterDelta(BytesToUint64(prev[0].Data), cur, counterBits)
+	if !ok {
+		return values, nil
+	}
+
+	rate, err := Float64ToBytes(float64(delta) / elapsed.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert: %w", err)
+	}
+
+	return append(values, Value{Name: "rate", Type: "float", Data: rate}), nil
+}
+
+// CollectNet collects a net key, rates are computed from
+// the previous values of the metric.
+func CollectNet(ctx context.Context, m *Metric) error {
+	values, err := NetByKey(ctx, m.Key, m.LastValue, time.Since(m.LastCheck))
+	if err != nil {
+		return err
+	}
+
+	m.LastValue = values
+
+	return nil
+}
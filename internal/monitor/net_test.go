@@ -0,0 +1,35 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name  string
+		prev  uint64
+		cur   uint64
+		bits  int
+		delta uint64
+		ok    bool
+	}{
+		{name: "increase", prev: 100, cur: 250, bits: 64, delta: 150, ok: true},
+		{name: "unchanged", prev: 100, cur: 100, bits: 64, delta: 0, ok: true},
+		{name: "64-bit reset", prev: 3e9, cur: 100, bits: 64, ok: false},
+		{name: "64-bit reset above 32 bits", prev: 1 << 40, cur: 100, bits: 64, ok: false},
+		{name: "32-bit wrap", prev: math.MaxUint32 - 9, cur: 20, bits: 32, delta: 30, ok: true},
+		{name: "32-bit reset", prev: 1e9, cur: 100, bits: 32, ok: false},
+		{name: "32-bit counter above 32 bits", prev: 1 << 40, cur: 100, bits: 32, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, ok := counterDelta(tt.prev, tt.cur, tt.bits)
+			if ok != tt.ok || delta != tt.delta {
+				t.Errorf("counterDelta(%d, %d, %d) = %d, %t, want %d, %t",
+					tt.prev, tt.cur, tt.bits, delta, ok, tt.delta, tt.ok)
+			}
+		})
+	}
+}
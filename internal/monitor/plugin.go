@@ -0,0 +1,290 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	log "log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+	"github.com/kirill-shtrykov/minimon/pkg/plugin"
+)
+
+const (
+	minPluginBackoff  = time.Second
+	maxPluginBackoff  = time.Minute
+	pluginQueue       = 16
+	maxPluginResponse = 1 << 20
+	pluginStopGrace   = 5 * time.Second
+)
+
+type pluginProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	enc       *json.Encoder
+	responses chan plugin.Message
+	exited    chan struct{}
+}
+
+// read passes messages of the plugin to responses until its output
+// ends, then reaps the process and closes responses and exited.
+func (p *pluginProcess) read(key string, stdout io.Reader) {
+	defer close(p.exited)
+	defer close(p.responses)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxPluginResponse)
+
+	for scanner.Scan() {
+		var m plugin.Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			log.Warn("malformed plugin message", log.String("key", key), log.Any("error", err))
+
+			continue
+		}
+
+		select {
+		case p.responses <- m:
+		default:
+			log.Warn("dropping plugin message", log.String("key", key), log.Uint64("id", m.ID))
+		}
+	}
+
+	err := p.cmd.Wait()
+	log.Warn("plugin exited", log.String("key", key), log.Any("status", err))
+}
+
+// await returns the response to the request id, stale responses
+// to requests given up on are skipped.
+func (p *pluginProcess) await(ctx context.Context, id uint64) (plugin.Message, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return plugin.Message{}, fmt.Errorf("%w: %w", ErrTimeoutError, ctx.Err())
+		case m, ok := <-p.responses:
+			if !ok {
+				return plugin.Message{}, ErrPluginExitedError
+			}
+
+			if m.ID == id {
+				return m, nil
+			}
+		}
+	}
+}
+
+// stop closes the input of the plugin, which then should exit,
+// and kills it if it hasn't after grace.
+func (p *pluginProcess) stop(grace time.Duration) {
+	p.stdin.Close()
+
+	go func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-p.exited:
+		case <-timer.C:
+			_ = p.cmd.Process.Kill()
+		}
+	}()
+}
+
+// PluginCollector keeps a plugin process running and collects from
+// it over the plugin protocol. A plugin that exits, fails to answer
+// in time or breaks the protocol is restarted on a later collection,
+// waiting twice as long after every consecutive failure.
+// A plugin is stopped by closing its input and killed if it does
+// not exit in time.
+type PluginCollector struct {
+	key     string
+	valType string
+	command []string
+	config  json.RawMessage
+	timeout time.Duration
+	grace   time.Duration
+	desc    collector.Desc
+	proc    *pluginProcess
+	lastID  uint64
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func (c *PluginCollector) start(ctx context.Context) error {
+	cmd := exec.Command(c.command[0], c.command[1:]...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open plugin input: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open plugin output: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin: %w", err)
+	}
+
+	p := &pluginProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		enc:       json.NewEncoder(stdin),
+		responses: make(chan plugin.Message, pluginQueue),
+		exited:    make(chan struct{}),
+	}
+
+	go p.read(c.key, stdout)
+
+	if err := c.handshake(ctx, p); err != nil {
+		p.stop(c.grace)
+
+		return err
+	}
+
+	c.proc = p
+
+	log.InfoContext(ctx, "plugin started", log.String("key", c.key), log.Int("pid", cmd.Process.Pid))
+
+	return nil
+}
+
+func (c *PluginCollector) handshake(ctx context.Context, p *pluginProcess) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	err := p.enc.Encode(plugin.Message{Type: plugin.TypeHandshake, Version: plugin.Version, Key: c.key, Config: c.config})
+	if err != nil {
+		return fmt.Errorf("failed to send handshake: %w", err)
+	}
+
+	m, err := p.await(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("failed to handshake: %w", err)
+	}
+
+	if m.Type != plugin.TypeDescribe || m.Desc == nil {
+		return fmt.Errorf("%w: handshake answered with %s: %s", plugin.ErrProtocolError, m.Type, m.Error)
+	}
+
+	if m.Version != plugin.Version {
+		return fmt.Errorf("%w: %d", plugin.ErrVersionMismatchError, m.Version)
+	}
+
+	c.desc = *m.Desc
+	c.desc.Key = c.key
+
+	return nil
+}
+
+// fail stops the plugin and schedules its restart.
+func (c *PluginCollector) fail(ctx context.Context, err error) {
+	if c.proc != nil {
+		c.proc.stop(c.grace)
+		c.proc = nil
+	}
+
+	c.backoff = min(max(c.backoff*2, minPluginBackoff), maxPluginBackoff)
+	c.retryAt = time.Now().Add(c.backoff)
+
+	log.WarnContext(ctx, "plugin failed", log.String("key", c.key), log.Duration("restart_in", c.backoff), log.Any("error", err))
+}
+
+func (c *PluginCollector) Describe() collector.Desc {
+	return c.desc
+}
+
+func (c *PluginCollector) Collect(ctx context.Context) ([]collector.Sample, error) {
+	if c.proc == nil {
+		if wait := time.Until(c.retryAt); wait > 0 {
+			return nil, fmt.Errorf("%w: restarting in %s", ErrPluginDownError, wait.Round(time.Second))
+		}
+
+		if err := c.start(ctx); err != nil {
+			c.fail(ctx, err)
+
+			return nil, err
+		}
+	}
+
+	c.lastID++
+
+	if err := c.proc.enc.Encode(plugin.Message{Type: plugin.TypeCollect, ID: c.lastID}); err != nil {
+		err = fmt.Errorf("failed to send collect: %w", err)
+		c.fail(ctx, err)
+
+		return nil, err
+	}
+
+	m, err := c.proc.await(ctx, c.lastID)
+	if err != nil {
+		c.fail(ctx, err)
+
+		return nil, err
+	}
+
+	c.backoff = 0
+
+	switch m.Type {
+	case plugin.TypeSamples:
+		return c.samples(m.Samples), nil
+	case plugin.TypeError:
+		return nil, fmt.Errorf("%w: %s", ErrPluginFailedError, m.Error)
+	}
+
+	return nil, fmt.Errorf("%w: collect answered with %s", plugin.ErrProtocolError, m.Type)
+}
+
+// samples turns JSON numbers of int samples back into ints and adds
+// the labels of the description, only known after the handshake.
+func (c *PluginCollector) samples(samples []collector.Sample) []collector.Sample {
+	for i, s := range samples {
+		samples[i].Labels = Labels(c.desc.Labels).With(s.Labels)
+
+		t := s.Type
+		if t == "" {
+			t = c.valType
+		}
+
+		if f, ok := s.Value.(float64); ok && t == "int" && f == math.Trunc(f) {
+			samples[i].Value = int(f)
+		}
+	}
+
+	return samples
+}
+
+func NewPluginCollector(key string, valType string, cfg conf.Plugin) (*PluginCollector, error) {
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("%w: command is required", ErrInvalidConfigError)
+	}
+
+	config, err := json.Marshal(cfg.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: options: %w", ErrInvalidConfigError, err)
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	return &PluginCollector{
+		key:     key,
+		valType: valType,
+		command: cfg.Command,
+		config:  config,
+		timeout: timeout,
+		grace:   pluginStopGrace,
+		desc:    collector.Desc{Key: key},
+	}, nil
+}
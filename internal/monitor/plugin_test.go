@@ -0,0 +1,242 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+	"github.com/kirill-shtrykov/minimon/pkg/plugin"
+)
+
+// pluginModeEnv makes the test binary run as a plugin,
+// see helperPlugin.
+const pluginModeEnv = "MINIMON_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(pluginModeEnv); mode != "" {
+		helperPlugin(mode)
+
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// testPlugin collects an int, an untyped and a float sample.
+type testPlugin struct{}
+
+func (testPlugin) Init(_ string, config json.RawMessage) (collector.Desc, error) {
+	var opts struct {
+		GPU string `json:"gpu"`
+	}
+
+	if err := json.Unmarshal(config, &opts); err != nil {
+		return collector.Desc{}, err
+	}
+
+	return collector.Desc{Names: []string{"n", "m", "f"}, Labels: map[string]string{"gpu": opts.GPU}}, nil
+}
+
+func (testPlugin) Collect(context.Context) ([]collector.Sample, error) {
+	return []collector.Sample{
+		collector.Int("n", 3),
+		{Name: "m", Value: 4, Labels: map[string]string{"fan": "1"}},
+		collector.Float("f", 2),
+	}, nil
+}
+
+// helperPlugin serves testPlugin or misbehaves as told by mode:
+// "version" answers the handshake with another version, "stale"
+// answers every collection twice with the stale answer first,
+// "exit" exits on collection, "hang" and "linger" never answer
+// it and "linger" ignores its input being closed.
+func helperPlugin(mode string) {
+	if mode == "ok" {
+		plugin.Main(testPlugin{})
+
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+
+	for scanner.Scan() {
+		var m plugin.Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			os.Exit(1)
+		}
+
+		if m.Type == plugin.TypeHandshake {
+			version := plugin.Version
+			if mode == "version" {
+				version++
+			}
+
+			_ = enc.Encode(plugin.Message{Type: plugin.TypeDescribe, Version: version, Desc: &collector.Desc{}})
+
+			continue
+		}
+
+		switch mode {
+		case "stale":
+			_ = enc.Encode(plugin.Message{Type: plugin.TypeSamples, ID: m.ID - 1, Samples: []collector.Sample{collector.Int("", 0)}})
+			_ = enc.Encode(plugin.Message{Type: plugin.TypeSamples, ID: m.ID, Samples: []collector.Sample{collector.Int("", int(m.ID))}})
+		case "exit":
+			os.Exit(1)
+		case "linger":
+			time.Sleep(time.Hour)
+		}
+	}
+}
+
+func newPluginCollector(t *testing.T, mode string, valType string) *PluginCollector {
+	t.Helper()
+	t.Setenv(pluginModeEnv, mode)
+
+	c, err := NewPluginCollector("gpu", valType, conf.Plugin{
+		Command: []string{os.Args[0], "-test.run=^$"},
+		Options: map[string]any{"gpu": "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if c.proc != nil {
+			c.proc.stop(0)
+		}
+	})
+
+	return c
+}
+
+func collectTimeout(c *PluginCollector, timeout time.Duration) ([]collector.Sample, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.Collect(ctx)
+}
+
+func TestPluginCollect(t *testing.T) {
+	c := newPluginCollector(t, "ok", "int")
+
+	samples, err := collectTimeout(c, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []collector.Sample{
+		{Name: "n", Type: "int", Value: 3, Labels: map[string]string{"gpu": "0"}},
+		{Name: "m", Value: 4, Labels: map[string]string{"gpu": "0", "fan": "1"}},
+		{Name: "f", Type: "float", Value: 2.0, Labels: map[string]string{"gpu": "0"}},
+	}
+
+	if !reflect.DeepEqual(samples, want) {
+		t.Fatalf("got %+v, want %+v", samples, want)
+	}
+
+	if desc := c.Describe(); desc.Key != "gpu" || len(desc.Names) != 3 {
+		t.Fatalf("got description %+v", desc)
+	}
+}
+
+func TestPluginVersionMismatch(t *testing.T) {
+	c := newPluginCollector(t, "version", "int")
+
+	if _, err := collectTimeout(c, 10*time.Second); !errors.Is(err, plugin.ErrVersionMismatchError) {
+		t.Fatalf("got %v, want %v", err, plugin.ErrVersionMismatchError)
+	}
+
+	if c.proc != nil || c.backoff != minPluginBackoff {
+		t.Fatalf("plugin not stopped, backoff %s", c.backoff)
+	}
+}
+
+func TestPluginStaleResponse(t *testing.T) {
+	c := newPluginCollector(t, "stale", "int")
+
+	for id := 1; id <= 3; id++ {
+		samples, err := collectTimeout(c, 10*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(samples) != 1 || samples[0].Value != id {
+			t.Fatalf("collection %d got %+v", id, samples)
+		}
+	}
+}
+
+func TestPluginRestart(t *testing.T) {
+	c := newPluginCollector(t, "exit", "int")
+
+	if _, err := collectTimeout(c, 10*time.Second); !errors.Is(err, ErrPluginExitedError) {
+		t.Fatalf("got %v, want %v", err, ErrPluginExitedError)
+	}
+
+	if _, err := collectTimeout(c, 10*time.Second); !errors.Is(err, ErrPluginDownError) {
+		t.Fatalf("got %v, want %v", err, ErrPluginDownError)
+	}
+
+	// restarted once the backoff passed
+	t.Setenv(pluginModeEnv, "ok")
+	c.retryAt = time.Now()
+
+	if _, err := collectTimeout(c, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.backoff != 0 {
+		t.Fatalf("got backoff %s after success", c.backoff)
+	}
+}
+
+func TestPluginTimeoutBackoff(t *testing.T) {
+	c := newPluginCollector(t, "hang", "int")
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if _, err := collectTimeout(c, 200*time.Millisecond); !errors.Is(err, ErrTimeoutError) {
+			t.Fatalf("got %v, want %v", err, ErrTimeoutError)
+		}
+
+		if c.backoff != want {
+			t.Fatalf("got backoff %s, want %s", c.backoff, want)
+		}
+
+		c.retryAt = time.Now()
+	}
+}
+
+func TestPluginStopKills(t *testing.T) {
+	c := newPluginCollector(t, "linger", "int")
+
+	if err := c.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	p := c.proc
+	c.proc = nil
+
+	if err := p.enc.Encode(plugin.Message{Type: plugin.TypeCollect, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	p.stop(100 * time.Millisecond)
+
+	select {
+	case <-p.exited:
+	case <-time.After(10 * time.Second):
+		t.Fatal("plugin not killed")
+	}
+
+	if state := p.cmd.ProcessState.String(); state != "signal: killed" {
+		t.Fatalf("got %s, want killed", state)
+	}
+}
@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultProcRoot = "/proc"
+
+// ReadLoadAvg parses 1, 5 and 15 minutes load averages
+// from the loadavg file under the procfs root.
+func ReadLoadAvg(root string) ([3]float64, error) {
+	var load [3]float64
+
+	raw, err := os.ReadFile(filepath.Join(root, "loadavg"))
+	if err != nil {
+		return load, fmt.Errorf("failed to read loadavg: %w", err)
+	}
+
+	fields := strings.Fields(string(raw))
+	if len(fields) < len(load) {
+		return load, fmt.Errorf("%w: loadavg: %q", ErrMalformedDataError, raw)
+	}
+
+	for i := range load {
+		load[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return load, fmt.Errorf("%w: loadavg: %w", ErrMalformedDataError, err)
+		}
+	}
+
+	return load, nil
+}
+
+// ReadUptime parses system uptime in seconds
+// from the uptime file under the procfs root.
+func ReadUptime(root string) (float64, error) {
+	raw, err := os.ReadFile(filepath.Join(root, "uptime"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read uptime: %w", err)
+	}
+
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: uptime: %q", ErrMalformedDataError, raw)
+	}
+
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: uptime: %w", ErrMalformedDataError, err)
+	}
+
+	return uptime, nil
+}
+
+// ReadPSI parses pressure stall information of the resource
+// (cpu, memory, io, irq) under the procfs root. The result is
+// indexed by line kind (some, full) and then by field
+// (avg10, avg60, avg300, total).
+func ReadPSI(root string, resource string) (map[string]map[string]float64, error) {
+	raw, err := os.ReadFile(filepath.Join(root, "pressure", resource))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pressure of %s: %w", resource, err)
+	}
+
+	psi := make(map[string]map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		values := make(map[string]float64, len(fields)-1)
+
+		for _, f := range fields[1:] {
+			name, v, ok := strings.Cut(f, "=")
+			if !ok {
+				return nil, fmt.Errorf("%w: pressure/%s: %q", ErrMalformedDataError, resource, f)
+			}
+
+			values[name], err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: pressure/%s: %w", ErrMalformedDataError, resource, err)
+			}
+		}
+
+		psi[fields[0]] = values
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan pressure of %s: %w", resource, err)
+	}
+
+	return psi, nil
+}
+
+func loadByKey(root string, key string) ([]byte, error) {
+	load, err := ReadLoadAvg(root)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key {
+	case "load.1":
+		return Float64ToBytes(load[0])
+	case "load.5":
+		return Float64ToBytes(load[1])
+	case "load.15":
+		return Float64ToBytes(load[2])
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+}
+
+// psiByKey handles keys in the form psi.<resource>.<some|full>.<field>.
+func psiByKey(root string, key string) ([]byte, error) {
+	k := strings.Split(key, ".")
+	if len(k) != 4 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	psi, err := ReadPSI(root, k[1])
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := psi[k[2]][k[3]]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	return Float64ToBytes(v)
+}
+
+// ProcByKey handles load.*, host.uptime and psi.* keys
+// reading files under the procfs root.
+func ProcByKey(root string, key string) ([][]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	switch k := strings.Split(key, "."); k[0] {
+	case "load":
+		b, err = loadByKey(root, key)
+	case "psi":
+		b, err = psiByKey(root, key)
+	case "host":
+		if key != "host.uptime" {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+		}
+
+		var uptime float64
+
+		uptime, err = ReadUptime(root)
+		if err == nil {
+			b, err = Float64ToBytes(uptime)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{b}, nil
+}
@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+)
+
+const procFixture = "testdata/proc"
+
+func TestReadLoadAvg(t *testing.T) {
+	load, err := ReadLoadAvg(procFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := [3]float64{0.52, 0.58, 0.59}; load != want {
+		t.Errorf("ReadLoadAvg() = %v, want %v", load, want)
+	}
+}
+
+func TestReadUptime(t *testing.T) {
+	uptime, err := ReadUptime(procFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uptime != 350735.47 {
+		t.Errorf("ReadUptime() = %v, want 350735.47", uptime)
+	}
+}
+
+func TestReadPSI(t *testing.T) {
+	psi, err := ReadPSI(procFixture, "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := psi["some"]["avg60"]; got != 0.10 {
+		t.Errorf("some avg60 = %v, want 0.10", got)
+	}
+
+	if got := psi["full"]["total"]; got != 1717 {
+		t.Errorf("full total = %v, want 1717", got)
+	}
+
+	if _, err := ReadPSI(procFixture, "io"); !errors.Is(err, ErrMalformedDataError) {
+		t.Errorf("ReadPSI(io) error = %v, want %v", err, ErrMalformedDataError)
+	}
+
+	if _, err := ReadPSI(procFixture, "irq"); err == nil {
+		t.Error("ReadPSI(irq) succeeded for a missing file")
+	}
+}
+
+func TestProcByKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want float64
+	}{
+		{key: "load.1", want: 0.52},
+		{key: "load.15", want: 0.59},
+		{key: "host.uptime", want: 350735.47},
+		{key: "psi.cpu.some.avg10", want: 1.5},
+		{key: "psi.memory.full.avg300", want: 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			bs, err := ProcByKey(procFixture, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := BytesToFloat64(bs[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(bs) != 1 || got != tt.want {
+				t.Errorf("ProcByKey(%s) = %v (%d values), want %v", tt.key, got, len(bs), tt.want)
+			}
+		})
+	}
+
+	for _, key := range []string{"load.2", "host.boot", "psi.cpu.some", "psi.cpu.half.avg10", "proc"} {
+		if _, err := ProcByKey(procFixture, key); !errors.Is(err, ErrUnknownKeyError) {
+			t.Errorf("ProcByKey(%s) error = %v, want %v", key, err, ErrUnknownKeyError)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/process"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// ProcessCollector tracks resource usage of the processes selected
+// by executable name, pidfile or command line pattern.
+type ProcessCollector struct {
+	name     string
+	pidfile  string
+	cmdline  *regexp.Regexp
+	procs    map[int32]*process.Process
+	seen     bool
+	restarts int
+}
+
+func (c *ProcessCollector) matches(ctx context.Context, p *process.Process) bool {
+	if c.name != "" {
+		name, err := p.NameWithContext(ctx)
+
+		return err == nil && name == c.name
+	}
+
+	cmdline, err := p.CmdlineWithContext(ctx)
+
+	return err == nil && c.cmdline.MatchString(cmdline)
+}
+
+func (c *ProcessCollector) find(ctx context.Context) (map[int32]*process.Process, error) {
+	found := make(map[int32]*process.Process)
+
+	if c.pidfile != "" {
+		raw, err := os.ReadFile(c.pidfile)
+		if errors.Is(err, fs.ErrNotExist) {
+			return found, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pidfile: %w", err)
+		}
+
+		pid, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pidfile %s: %w", ErrMalformedDataError, c.pidfile, err)
+		}
+
+		if ok, _ := process.PidExistsWithContext(ctx, int32(pid)); ok {
+			found[int32(pid)] = c.process(int32(pid))
+		}
+
+		return found, nil
+	}
+
+	pids, err := process.PidsWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	self := os.Getpid()
+
+	for _, pid := range pids {
+		if int(pid) == self {
+			continue
+		}
+
+		p := c.process(pid)
+		if c.matches(ctx, p) {
+			found[pid] = p
+		}
+	}
+
+	return found, nil
+}
+
+// process returns the process tracked on the previous collection,
+// so CPU percent is computed since then.
+func (c *ProcessCollector) process(pid int32) *process.Process {
+	if p, ok := c.procs[pid]; ok {
+		return p
+	}
+
+	return &process.Process{Pid: pid}
+}
+
+// restarted reports whether none of the previously running processes
+// survived while some are running now.
+func (c *ProcessCollector) restarted(found map[int32]*process.Process) bool {
+	if !c.seen || len(found) == 0 {
+		return false
+	}
+
+	for pid := range found {
+		if _, ok := c.procs[pid]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *ProcessCollector) Collect(ctx context.Context, m *Metric) error {
+	found, err := c.find(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.restarted(found) {
+		c.restarts++
+	}
+
+	c.procs = found
+	c.seen = c.seen || len(found) > 0
+
+	var (
+		cpuPercent float64
+		rss        uint64
+		fds        int
+		threads    int
+	)
+
+	for _, p := range found {
+		if v, err := p.PercentWithContext(ctx, 0); err == nil {
+			cpuPercent += v
+		}
+
+		if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+			rss += mem.RSS
+		}
+
+		if v, err := p.NumFDsWithContext(ctx); err == nil {
+			fds += int(v)
+		}
+
+		if v, err := p.NumThreadsWithContext(ctx); err == nil {
+			threads += int(v)
+		}
+	}
+
+	alive := len(found) > 0
+
+	values := []Value{
+		boolValue("alive", alive),
+		intValue("count", len(found)),
+		intValue("restarts", c.restarts),
+	}
+
+	if alive {
+		values = append(values,
+			floatValue("cpu_percent", cpuPercent),
+			uint64Value("rss", rss),
+			intValue("fds", fds),
+			intValue("threads", threads),
+		)
+	}
+
+	m.LastValue = values
+
+	return nil
+}
+
+func NewProcessCollector(cfg conf.Process) (*ProcessCollector, error) {
+	selectors := 0
+
+	for _, s := range []string{cfg.Name, cfg.Pidfile, cfg.Cmdline} {
+		if s != "" {
+			selectors++
+		}
+	}
+
+	if selectors != 1 {
+		return nil, fmt.Errorf("%w: exactly one of name, pidfile or cmdline is required", ErrInvalidConfigError)
+	}
+
+	c := &ProcessCollector{name: cfg.Name, pidfile: cfg.Pidfile}
+
+	if cfg.Cmdline != "" {
+		re, err := regexp.Compile(cfg.Cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cmdline: %w", ErrInvalidConfigError, err)
+		}
+
+		c.cmdline = re
+	}
+
+	return c, nil
+}
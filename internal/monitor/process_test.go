@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// startProcess starts the test binary as a plugin hanging until
+// it is killed, args are only there to be matched.
+func startProcess(t *testing.T, args ...string) *exec.Cmd {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$"}, args...)...)
+	cmd.Env = append(os.Environ(), pluginModeEnv+"=hang")
+
+	// keeps the plugin waiting for requests
+	if _, err := cmd.StdinPipe(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { stopProcess(cmd) })
+
+	return cmd
+}
+
+func stopProcess(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+}
+
+func writePidfile(t *testing.T, path string, cmd *exec.Cmd) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newProcessCollector(t *testing.T, cfg conf.Process) *ProcessCollector {
+	t.Helper()
+
+	c, err := NewProcessCollector(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestProcessSelectors(t *testing.T) {
+	marker := "minimon-process-" + strconv.Itoa(os.Getpid())
+	cmd := startProcess(t, marker)
+	pid := int32(cmd.Process.Pid)
+
+	pidfile := filepath.Join(t.TempDir(), "test.pid")
+	writePidfile(t, pidfile, cmd)
+
+	tests := []struct {
+		name string
+		cfg  conf.Process
+	}{
+		{name: "name", cfg: conf.Process{Name: filepath.Base(os.Args[0])}},
+		{name: "cmdline", cfg: conf.Process{Cmdline: marker + "$"}},
+		{name: "pidfile", cfg: conf.Process{Pidfile: pidfile}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newProcessCollector(t, tt.cfg)
+			values := collectValues(t, c.Collect)
+
+			if _, ok := c.procs[pid]; !ok {
+				t.Fatalf("process %d not found among %v", pid, c.procs)
+			}
+
+			if values["alive"] != 1 || values["restarts"] != 0 || values["threads"].(int) < 1 {
+				t.Fatalf("got %v", values)
+			}
+		})
+	}
+}
+
+func TestProcessNotRunning(t *testing.T) {
+	dir := t.TempDir()
+
+	c := newProcessCollector(t, conf.Process{Pidfile: filepath.Join(dir, "missing.pid")})
+	if values := collectValues(t, c.Collect); values["alive"] != 0 || values["count"] != 0 || len(values) != 3 {
+		t.Fatalf("got %v", values)
+	}
+
+	c = newProcessCollector(t, conf.Process{Cmdline: "minimon-process-none-" + strconv.Itoa(os.Getpid()) + "$"})
+	if values := collectValues(t, c.Collect); values["alive"] != 0 {
+		t.Fatalf("got %v", values)
+	}
+
+	malformed := filepath.Join(dir, "malformed.pid")
+	if err := os.WriteFile(malformed, []byte("nginx\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c = newProcessCollector(t, conf.Process{Pidfile: malformed})
+	if err := c.Collect(context.Background(), &Metric{}); !errors.Is(err, ErrMalformedDataError) {
+		t.Fatalf("got %v, want %v", err, ErrMalformedDataError)
+	}
+}
+
+func TestProcessRestarts(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "test.pid")
+	c := newProcessCollector(t, conf.Process{Pidfile: pidfile})
+
+	// not running yet is no restart
+	if values := collectValues(t, c.Collect); values["restarts"] != 0 {
+		t.Fatalf("got %v", values)
+	}
+
+	first := startProcess(t)
+	writePidfile(t, pidfile, first)
+
+	if values := collectValues(t, c.Collect); values["alive"] != 1 || values["restarts"] != 0 {
+		t.Fatalf("got %v", values)
+	}
+
+	stopProcess(first)
+
+	if values := collectValues(t, c.Collect); values["alive"] != 0 || values["restarts"] != 0 {
+		t.Fatalf("got %v", values)
+	}
+
+	second := startProcess(t)
+	writePidfile(t, pidfile, second)
+
+	if values := collectValues(t, c.Collect); values["alive"] != 1 || values["restarts"] != 1 {
+		t.Fatalf("got %v", values)
+	}
+
+	// the same process again
+	if values := collectValues(t, c.Collect); values["restarts"] != 1 {
+		t.Fatalf("got %v", values)
+	}
+}
+
+func TestNewProcessCollector(t *testing.T) {
+	for _, cfg := range []conf.Process{
+		{},
+		{Name: "nginx", Pidfile: "/run/nginx.pid"},
+		{Cmdline: "("},
+	} {
+		if _, err := NewProcessCollector(cfg); !errors.Is(err, ErrInvalidConfigError) {
+			t.Fatalf("%+v: got %v, want %v", cfg, err, ErrInvalidConfigError)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	log "log/slog"
+	"math"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// PromSample is a single series value of the Prometheus
+// text exposition format.
+type PromSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// parseLabels parses the `a="b",c="d"}` part of a series line and
+// returns the labels and the rest of the line after the closing brace.
+func parseLabels(s string) (map[string]string, string, error) {
+	labels := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(rest, `"`) {
+			return nil, "", fmt.Errorf("%w: labels: %q", ErrMalformedDataError, s)
+		}
+
+		var (
+			value   strings.Builder
+			escaped bool
+			end     = -1
+		)
+
+		for i, r := range rest[1:] {
+			if escaped {
+				if r == 'n' {
+					r = '\n'
+				}
+
+				value.WriteRune(r)
+
+				escaped = false
+
+				continue
+			}
+
+			if r == '\\' {
+				escaped = true
+
+				continue
+			}
+
+			if r == '"' {
+				end = i + 2
+
+				break
+			}
+
+			value.WriteRune(r)
+		}
+
+		if end < 0 {
+			return nil, "", fmt.Errorf("%w: unterminated label value: %q", ErrMalformedDataError, s)
+		}
+
+		labels[strings.TrimSpace(name)] = value.String()
+		s = rest[end:]
+	}
+}
+
+func parsePromLine(line string) (PromSample, error) {
+	var (
+		sample PromSample
+		rest   string
+		err    error
+	)
+
+	i := strings.IndexAny(line, "{ \t")
+	if i < 0 {
+		return sample, fmt.Errorf("%w: no value: %q", ErrMalformedDataError, line)
+	}
+
+	sample.Name, rest = line[:i], line[i:]
+
+	if line[i] == '{' {
+		sample.Labels, rest, err = parseLabels(line[i+1:])
+		if err != nil {
+			return sample, err
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("%w: no value: %q", ErrMalformedDataError, line)
+	}
+
+	sample.Value, err = strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("%w: %w", ErrMalformedDataError, err)
+	}
+
+	return sample, nil
+}
+
+// ParsePrometheusText parses the Prometheus text exposition format.
+// Comments, type hints and timestamps are ignored. Malformed lines
+// are skipped, their errors are returned along with the samples.
+func ParsePrometheusText(r io.Reader) ([]PromSample, []error, error) {
+	var (
+		samples []PromSample
+		skipped []error
+	)
+
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample, err := parsePromLine(line)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("line %d: %w", n, err))
+
+			continue
+		}
+
+		samples = append(samples, sample)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to scan metrics: %w", err)
+	}
+
+	return samples, skipped, nil
+}
+
+// PrometheusCollector scrapes a Prometheus exporter and maps the
+// selected series into sub-keys `<name>[.<suffix>]`.
+type PrometheusCollector struct {
+	client   *http.Client
+	url      string
+	headers  map[string]string
+	match    string
+	regex    *regexp.Regexp
+	template *template.Template
+	// collisions are sub-keys already warned about
+	collisions map[string]bool
+}
+
+func (c *PrometheusCollector) selected(name string) bool {
+	if c.regex != nil {
+		return c.regex.MatchString(name)
+	}
+
+	ok, _ := path.Match(c.match, name)
+
+	return ok
+}
+
+func (c *PrometheusCollector) suffix(labels map[string]string) (string, error) {
+	if c.template != nil {
+		var b strings.Builder
+		if err := c.template.Execute(&b, labels); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+
+		return b.String(), nil
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = labels[name]
+	}
+
+	return strings.Join(values, "."), nil
+}
+
+func (c *PrometheusCollector) scrape(ctx context.Context) ([]PromSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/plain")
+
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedStatusError, resp.Status)
+	}
+
+	samples, skipped, err := ParsePrometheusText(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(skipped) > 0 {
+		log.WarnContext(ctx, "skipped malformed exposition lines",
+			log.String("url", c.url), log.Int("count", len(skipped)), log.Any("first", skipped[0]))
+	}
+
+	return samples, nil
+}
+
+func (c *PrometheusCollector) Collect(ctx context.Context, m *Metric) error {
+	samples, err := c.scrape(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+
+	var values []Value
+
+	for _, s := range samples {
+		if !c.selected(s.Name) || math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+
+		suffix, err := c.suffix(s.Labels)
+		if err != nil {
+			return err
+		}
+
+		name := s.Name
+		if suffix != "" {
+			name = name + "." + suffix
+		}
+
+		if seen[name] {
+			if !c.collisions[name] {
+				c.collisions[name] = true
+
+				log.WarnContext(ctx, "series collide on a sub-key, only the first one is imported",
+					log.String("url", c.url), log.String("name", name))
+			}
+
+			continue
+		}
+
+		seen[name] = true
+		values = append(values, floatValue(name, s.Value))
+	}
+
+	m.LastValue = values
+
+	return nil
+}
+
+func NewPrometheusCollector(cfg conf.Prometheus) (*PrometheusCollector, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidConfigError)
+	}
+
+	timeout := defaultProbeTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	if cfg.Match == "" && cfg.Regex == "" {
+		return nil, fmt.Errorf("%w: match or regex is required, match \"*\" imports every series", ErrInvalidConfigError)
+	}
+
+	c := &PrometheusCollector{
+		client:     &http.Client{Timeout: timeout},
+		url:        cfg.URL,
+		headers:    cfg.Headers,
+		match:      cfg.Match,
+		collisions: make(map[string]bool),
+	}
+
+	if cfg.Match != "" {
+		if _, err := path.Match(cfg.Match, ""); err != nil {
+			return nil, fmt.Errorf("%w: match: %w", ErrInvalidConfigError, err)
+		}
+	}
+
+	if cfg.Regex != "" {
+		re, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%w: regex: %w", ErrInvalidConfigError, err)
+		}
+
+		c.regex = re
+	}
+
+	if cfg.Template != "" {
+		t, err := template.New("suffix").Option("missingkey=zero").Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("%w: template: %w", ErrInvalidConfigError, err)
+		}
+
+		c.template = t
+	}
+
+	return c, nil
+}
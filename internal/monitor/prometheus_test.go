@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const promFixture = "testdata/prometheus/node.prom"
+
+func TestParsePrometheusText(t *testing.T) {
+	f, err := os.Open(promFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	samples, skipped, err := ParsePrometheusText(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(skipped) != 2 {
+		t.Errorf("skipped %d lines (%v), want 2", len(skipped), skipped)
+	}
+
+	for _, err := range skipped {
+		if !errors.Is(err, ErrMalformedDataError) {
+			t.Errorf("skipped line error = %v, want %v", err, ErrMalformedDataError)
+		}
+	}
+
+	if len(samples) != 10 {
+		t.Fatalf("parsed %d samples, want 10", len(samples))
+	}
+
+	tests := []struct {
+		i      int
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{i: 0, name: "node_load1", value: 0.52},
+		{i: 1, name: "node_filesystem_avail_bytes", labels: map[string]string{
+			"device": "/dev/sda1", "fstype": "ext4", "mountpoint": "/",
+		}, value: 1.2e10},
+		{i: 2, name: "node_filesystem_avail_bytes", labels: map[string]string{
+			"device": "/dev/sda2", "fstype": "ext4", "mountpoint": "/home",
+		}, value: 3.5e9},
+		{i: 3, name: "node_network_receive_bytes_total", labels: map[string]string{"device": "eth0"}, value: 12345},
+		{i: 5, name: "node_textfile_info", labels: map[string]string{"path": `C:\tmp\a "quoted" file`}, value: 1},
+	}
+
+	for _, tt := range tests {
+		s := samples[tt.i]
+		if s.Name != tt.name || s.Value != tt.value || (len(tt.labels) > 0 && !reflect.DeepEqual(s.Labels, tt.labels)) {
+			t.Errorf("sample %d = %+v, want %s%v %v", tt.i, s, tt.name, tt.labels, tt.value)
+		}
+	}
+
+	if !math.IsNaN(samples[6].Value) || !math.IsInf(samples[7].Value, 1) {
+		t.Errorf("special values = %v, %v, want NaN, +Inf", samples[6].Value, samples[7].Value)
+	}
+}
+
+func TestPrometheusCollect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, promFixture)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		cfg  conf.Prometheus
+		want map[string]any
+	}{
+		{
+			name: "match",
+			cfg:  conf.Prometheus{Match: "node_network_*"},
+			want: map[string]any{
+				"node_network_receive_bytes_total.eth0": 12345.0,
+				"node_network_receive_bytes_total.lo":   678.0,
+			},
+		},
+		{
+			name: "regex and template",
+			cfg:  conf.Prometheus{Regex: "^node_filesystem_", Template: "{{.mountpoint}}"},
+			want: map[string]any{
+				"node_filesystem_avail_bytes./":     1.2e10,
+				"node_filesystem_avail_bytes./home": 3.5e9,
+			},
+		},
+		{
+			name: "collision and non-finite values",
+			cfg:  conf.Prometheus{Regex: "^node_(uname_info|scrape_error|boot_time_seconds)$", Template: "{{.machine}}"},
+			want: map[string]any{"node_uname_info.x86_64": 1.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.URL = srv.URL
+
+			c, err := NewPrometheusCollector(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if values := collectValues(t, c.Collect); !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("Collect() = %v, want %v", values, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewPrometheusCollector(conf.Prometheus{URL: srv.URL}); !errors.Is(err, ErrInvalidConfigError) {
+		t.Errorf("NewPrometheusCollector() without match error = %v, want %v", err, ErrInvalidConfigError)
+	}
+}
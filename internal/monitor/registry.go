@@ -0,0 +1,327 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+)
+
+type handler = func(ctx context.Context, metric *Metric) error
+
+// builtin adapts the collectors of this package, which report
+// encoded values through a Metric, to collector.Collector. The
+// service collects the metric of a builtin itself, so there is
+// a single Metric holding the state between collections.
+type builtin struct {
+	metric *Metric
+	desc   collector.Desc
+}
+
+func newBuiltin(cfg collector.Config, h handler) *builtin {
+	return &builtin{
+		metric: &Metric{Key: cfg.Key, Type: cfg.Type, Root: cfg.Root, HandlerFunc: h},
+		desc:   collector.Desc{Key: cfg.Key},
+	}
+}
+
+func (b *builtin) Describe() collector.Desc {
+	return b.desc
+}
+
+func (b *builtin) Collect(ctx context.Context) ([]collector.Sample, error) {
+	if err := b.metric.Handler(ctx); err != nil {
+		return nil, err
+	}
+
+	values := b.metric.LastValue
+	samples := make([]collector.Sample, len(values))
+
+	for i, v := range values {
+		t := b.metric.Type
+		if v.Type != "" {
+			t = v.Type
+		}
+
+		value, err := fromBytes(v.Data, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", v.Name, err)
+		}
+
+		samples[i] = collector.Sample{Name: v.Name, Type: t, Value: value, Labels: v.Labels}
+	}
+
+	return samples, nil
+}
+
+// toInt returns an integer of any kind as int64, false for other
+// values. Unsigned integers above math.MaxInt64 are out of range.
+func toInt(v any) (int64, bool, error) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), true, nil
+	case int8:
+		return int64(i), true, nil
+	case int16:
+		return int64(i), true, nil
+	case int32:
+		return int64(i), true, nil
+	case int64:
+		return i, true, nil
+	case uint:
+		return toInt(uint64(i))
+	case uint8:
+		return int64(i), true, nil
+	case uint16:
+		return int64(i), true, nil
+	case uint32:
+		return int64(i), true, nil
+	case uint64:
+		if i > math.MaxInt64 {
+			return 0, true, fmt.Errorf("%w: %d", ErrIntegerOutOfRange, i)
+		}
+
+		return int64(i), true, nil
+	}
+
+	return 0, false, nil
+}
+
+func toBytes(v any, t string) ([]byte, error) {
+	i, isInt, err := toInt(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case "string":
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	case "int":
+		if isInt {
+			return binary.LittleEndian.AppendUint64(nil, uint64(i)), nil
+		}
+	case "float":
+		switch f := v.(type) {
+		case float64:
+			return Float64ToBytes(f)
+		case float32:
+			return Float64ToBytes(float64(f))
+		}
+
+		if isInt {
+			return Float64ToBytes(float64(i))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownValueType, t)
+	}
+
+	return nil, fmt.Errorf("%w: %T is not %s", ErrMalformedDataError, v, t)
+}
+
+// collectorHandler runs a collector as a metric handler.
+func collectorHandler(c collector.Collector) handler {
+	return func(ctx context.Context, m *Metric) error {
+		samples, err := c.Collect(ctx)
+		if err != nil {
+			return err
+		}
+
+		values := make([]Value, len(samples))
+
+		for i, s := range samples {
+			t := m.Type
+			if s.Type != "" {
+				t = s.Type
+			}
+
+			b, err := toBytes(s.Value, t)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", s.Name, err)
+			}
+
+			for name := range s.Labels {
+				if !isLabelName(name) {
+					return fmt.Errorf("%w: label name %q of %s", ErrMalformedDataError, name, s.Name)
+				}
+			}
+
+			values[i] = Value{Name: s.Name, Type: t, Data: b, Labels: s.Labels}
+		}
+
+		m.LastValue = values
+
+		return nil
+	}
+}
+
+// indexedHandler is the handler of collectors returning raw values.
+func indexedHandler(collect func(ctx context.Context, m *Metric) ([][]byte, error)) handler {
+	return func(ctx context.Context, m *Metric) error {
+		v, err := collect(ctx, m)
+		if err != nil {
+			return err
+		}
+
+		m.LastValue = indexed(v)
+
+		return nil
+	}
+}
+
+func family(h handler) collector.Factory {
+	return func(cfg collector.Config) (collector.Collector, error) {
+		b := newBuiltin(cfg, h)
+		b.desc.Key, b.desc.Labels = objectDesc(cfg.Key)
+
+		return b, nil
+	}
+}
+
+// configured is the factory of a collector set up from the
+// method section of the metric configuration.
+func configured[T any](build func(cfg T, valType string) (handler, error)) collector.Factory {
+	return func(cfg collector.Config) (collector.Collector, error) {
+		var section T
+		if err := cfg.Decode(&section); err != nil {
+			return nil, err
+		}
+
+		h, err := build(section, cfg.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		return newBuiltin(cfg, h), nil
+	}
+}
+
+func families() map[string]handler {
+	procfs := indexedHandler(func(_ context.Context, m *Metric) ([][]byte, error) {
+		return ProcByKey(rootOr(m.Root, defaultProcRoot), m.Key)
+	})
+
+	return map[string]handler{
+		"cpu": indexedHandler(func(ctx context.Context, m *Metric) ([][]byte, error) {
+			return CPUByKey(ctx, m.Key)
+		}),
+		"mem": indexedHandler(func(ctx context.Context, m *Metric) ([][]byte, error) {
+			return MemByKey(ctx, m.Key)
+		}),
+		"swap": indexedHandler(func(ctx context.Context, m *Metric) ([][]byte, error) {
+			return SwapByKey(ctx, m.Key)
+		}),
+		"disk": indexedHandler(func(ctx context.Context, m *Metric) ([][]byte, error) {
+			return DiskByKey(ctx, m.Key)
+		}),
+		"load": procfs,
+		"host": procfs,
+		"psi":  procfs,
+		"net":  CollectNet,
+		"sensor": func(_ context.Context, m *Metric) error {
+			values, err := SensorByKey(rootOr(m.Root, defaultSysRoot), m.Key)
+			if err != nil {
+				return err
+			}
+
+			m.LastValue = values
+
+			return nil
+		},
+	}
+}
+
+func methods() map[string]collector.Factory {
+	return map[string]collector.Factory{
+		"process": configured(func(cfg conf.Process, _ string) (handler, error) {
+			c, err := NewProcessCollector(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"exec": configured(func(cfg conf.Exec, valType string) (handler, error) {
+			c, err := NewExecCollector(cfg, valType)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"http": configured(func(cfg conf.HTTP, _ string) (handler, error) {
+			c, err := NewHTTPCollector(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"tcp": configured(func(cfg conf.TCP, _ string) (handler, error) {
+			c, err := NewTCPCollector(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"tls": configured(func(cfg conf.TLS, _ string) (handler, error) {
+			c, err := NewTLSCollector(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"dns": configured(func(cfg conf.DNS, _ string) (handler, error) {
+			c, err := NewDNSCollector(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"prometheus": configured(func(cfg conf.Prometheus, _ string) (handler, error) {
+			c, err := NewPrometheusCollector(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return c.Collect, nil
+		}),
+		"plugin": func(cfg collector.Config) (collector.Collector, error) {
+			var section conf.Plugin
+			if err := cfg.Decode(&section); err != nil {
+				return nil, err
+			}
+
+			return NewPluginCollector(cfg.Key, cfg.Type, section)
+		},
+	}
+}
+
+// Register registers the built-in collection methods and, for the
+// internal method, key families.
+func Register(reg *collector.Registry) error {
+	if err := reg.Register("internal", reg.NewFamily); err != nil {
+		return fmt.Errorf("failed to register collectors: %w", err)
+	}
+
+	for method, f := range methods() {
+		if err := reg.Register(method, f); err != nil {
+			return fmt.Errorf("failed to register collectors: %w", err)
+		}
+	}
+
+	for prefix, h := range families() {
+		if err := reg.RegisterFamily(prefix, family(h)); err != nil {
+			return fmt.Errorf("failed to register collectors: %w", err)
+		}
+	}
+
+	return nil
+}
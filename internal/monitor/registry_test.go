@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+)
+
+func TestToBytes(t *testing.T) {
+	tests := []struct {
+		v    any
+		typ  string
+		want any
+	}{
+		{v: -3, typ: "int", want: -3},
+		{v: int8(-8), typ: "int", want: -8},
+		{v: int32(math.MinInt32), typ: "int", want: math.MinInt32},
+		{v: int64(math.MaxInt64), typ: "int", want: math.MaxInt64},
+		{v: uint8(255), typ: "int", want: 255},
+		{v: uint64(1 << 40), typ: "int", want: 1 << 40},
+		{v: int16(-2), typ: "float", want: -2.0},
+		{v: float32(0.5), typ: "float", want: 0.5},
+		{v: "up", typ: "string", want: "up"},
+	}
+
+	for _, tt := range tests {
+		b, err := toBytes(tt.v, tt.typ)
+		if err != nil {
+			t.Fatalf("%T %v: %v", tt.v, tt.v, err)
+		}
+
+		if got := decoded(t, Value{Data: b}, tt.typ); got != tt.want {
+			t.Fatalf("%T %v: got %v, want %v", tt.v, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestToBytesErrors(t *testing.T) {
+	tests := []struct {
+		v   any
+		typ string
+		err error
+	}{
+		{v: uint64(math.MaxUint64), typ: "int", err: ErrIntegerOutOfRange},
+		{v: 1.5, typ: "int", err: ErrMalformedDataError},
+		{v: 1, typ: "string", err: ErrMalformedDataError},
+		{v: 1, typ: "bool", err: ErrUnknownValueType},
+	}
+
+	for _, tt := range tests {
+		if _, err := toBytes(tt.v, tt.typ); !errors.Is(err, tt.err) {
+			t.Fatalf("%T %v as %s: got %v, want %v", tt.v, tt.v, tt.typ, err, tt.err)
+		}
+	}
+}
+
+func TestBuiltinMetric(t *testing.T) {
+	reg := collector.NewRegistry()
+
+	var b *builtin
+
+	err := reg.Register("count", func(cfg collector.Config) (collector.Collector, error) {
+		// counts collections in the metric state
+		b = newBuiltin(cfg, func(_ context.Context, m *Metric) error {
+			n := 0
+			if len(m.LastValue) > 0 {
+				n = decoded(t, m.LastValue[0], "int").(int)
+			}
+
+			m.LastValue = []Value{intValue("n", n+1)}
+
+			return nil
+		})
+
+		return b, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(nil, reg, []conf.Metric{{Key: "count", Method: "count", Type: "int", Interval: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.metrics[0]
+	if m != b.metric {
+		t.Fatal("service and collector have their own metric")
+	}
+
+	if err := m.Handler(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := b.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 1 || samples[0].Value != 2 || m.Series != "count" || m.LastCheck.IsZero() {
+		t.Fatalf("got %+v of %+v", samples, m)
+	}
+}
@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const defaultPruneBatch = 1000
+
+type retentionRule struct {
+	pattern string
+	sel     Selector
+	keep    time.Duration
+}
+
+// matches reports whether the rule applies to the series. A rule
+// without label matchers also matches the key the series was stored
+// under before labels, so `cpu.percent.thread.*` still applies
+// to the threads.
+func (rule retentionRule) matches(key string, labels Labels) bool {
+	if len(rule.sel.Matchers) == 0 && matchKey(rule.sel.Key, legacyKey(key, labels)) {
+		return true
+	}
+
+	return matchKey(rule.sel.Key, key) && rule.sel.MatchLabels(labels)
+}
+
+// Retention decides how long values of a series
+// and rollups of a resolution are kept.
+type Retention struct {
+	def     time.Duration
+	rules   []retentionRule
+	rollups map[time.Duration]time.Duration
+	batch   int
+}
+
+// matchKey reports whether the key matches the glob pattern,
+// where `*` matches any sequence of characters including dots.
+func matchKey(pattern string, key string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == key
+	}
+
+	if !strings.HasPrefix(key, parts[0]) {
+		return false
+	}
+
+	key = key[len(parts[0]):]
+	last := parts[len(parts)-1]
+
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(key, p)
+		if i < 0 {
+			return false
+		}
+
+		key = key[i+len(p):]
+	}
+
+	return len(key) >= len(last) && strings.HasSuffix(key, last)
+}
+
+// For returns the retention of the series, zero means forever.
+func (r Retention) For(key string, labels Labels) time.Duration {
+	for _, rule := range r.rules {
+		if rule.matches(key, labels) {
+			return rule.keep
+		}
+	}
+
+	return r.def
+}
+
+func NewRetention(cfg conf.Retention) (Retention, error) {
+	r := Retention{
+		def:     time.Duration(cfg.Default),
+		rollups: make(map[time.Duration]time.Duration),
+		batch:   cfg.Batch,
+	}
+
+	for res, keep := range cfg.Rollups {
+		d, err := conf.ParseDuration(res)
+		if err != nil {
+			return r, fmt.Errorf("%w: rollup resolution: %w", ErrInvalidConfigError, err)
+		}
+
+		if !slices.Contains(rollupLevels(), d) {
+			return r, fmt.Errorf("%w: there are no %s rollups", ErrInvalidConfigError, res)
+		}
+
+		r.rollups[d] = time.Duration(keep)
+	}
+
+	if r.batch <= 0 {
+		r.batch = defaultPruneBatch
+	}
+
+	for pattern, keep := range cfg.Keys {
+		sel, err := ParseSelector(pattern)
+		if err != nil {
+			return r, fmt.Errorf("%w: retention of %q: %w", ErrInvalidConfigError, pattern, err)
+		}
+
+		if sel.Key == "" {
+			return r, fmt.Errorf("%w: retention of %q: key is required", ErrInvalidConfigError, pattern)
+		}
+
+		r.rules = append(r.rules, retentionRule{pattern: pattern, sel: sel, keep: time.Duration(keep)})
+	}
+
+	// The pattern with more label matchers is the most specific,
+	// then the one with the longest literal part of the key.
+	sort.Slice(r.rules, func(i, j int) bool {
+		if mi, mj := len(r.rules[i].sel.Matchers), len(r.rules[j].sel.Matchers); mi != mj {
+			return mi > mj
+		}
+
+		li := len(strings.ReplaceAll(r.rules[i].sel.Key, "*", ""))
+		lj := len(strings.ReplaceAll(r.rules[j].sel.Key, "*", ""))
+
+		if li != lj {
+			return li > lj
+		}
+
+		return r.rules[i].pattern < r.rules[j].pattern
+	})
+
+	return r, nil
+}
+
+// Prune removes values older than their retention in batches, so
+// writers are not locked out for long, and returns removed rows count.
+func (s *Service) Prune(ctx context.Context, r Retention) (int64, error) {
+	total, err := s.pruneRollups(ctx, r)
+	if err != nil {
+		return total, err
+	}
+
+	if r.def <= 0 && len(r.rules) == 0 {
+		return total, nil
+	}
+
+	series, err := s.repo.ListSeries(ctx)
+	if err != nil {
+		return total, fmt.Errorf("failed to prune: %w", err)
+	}
+
+	cache := make(labelCache)
+
+	for _, ser := range series {
+		labels, err := cache.parse(ser.Labels)
+		if err != nil {
+			return total, fmt.Errorf("failed to prune %s%s: %w", ser.Key, ser.Labels, err)
+		}
+
+		keep := r.For(ser.Key, labels)
+		if keep <= 0 {
+			continue
+		}
+
+		cutoff := time.Now().Add(-keep)
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, fmt.Errorf("prune interrupted: %w", err)
+			}
+
+			n, err := s.repo.DeleteBefore(ctx, ser.ID, cutoff, r.batch)
+			if err != nil {
+				return total, fmt.Errorf("failed to prune %s%s: %w", ser.Key, ser.Labels, err)
+			}
+
+			total += n
+
+			if n < int64(r.batch) {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
+
+func (s *Service) pruneRollups(ctx context.Context, r Retention) (int64, error) {
+	var total int64
+
+	for res, keep := range r.rollups {
+		if keep <= 0 {
+			continue
+		}
+
+		cutoff := wallUnix(time.Now().Add(-keep))
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, fmt.Errorf("prune interrupted: %w", err)
+			}
+
+			n, err := s.repo.DeleteRollupsBefore(ctx, int64(res/time.Second), cutoff, r.batch)
+			if err != nil {
+				return total, fmt.Errorf("failed to prune %s rollups: %w", res, err)
+			}
+
+			total += n
+
+			if n < int64(r.batch) {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
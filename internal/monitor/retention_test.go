@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+func newRetention(t *testing.T, cfg conf.Retention) Retention {
+	t.Helper()
+
+	r, err := NewRetention(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestRetentionFor(t *testing.T) {
+	r := newRetention(t, conf.Retention{
+		Default: conf.Duration(30 * 24 * time.Hour),
+		Keys: map[string]conf.Duration{
+			"*":                                  conf.Duration(90 * 24 * time.Hour),
+			"cpu.*":                              conf.Duration(7 * 24 * time.Hour),
+			"cpu.percent.thread.*":               conf.Duration(24 * time.Hour),
+			`cpu.percent.thread{index=~"[0-1]"}`: conf.Duration(12 * time.Hour),
+			"*.thread.*":                         conf.Duration(48 * time.Hour),
+			`disk.percent{mountpoint!="/"}`:      conf.Duration(time.Hour),
+			"net.*.eth0":                         conf.Duration(2 * time.Hour),
+			"host.uptime":                        0,
+		},
+	})
+
+	tests := []struct {
+		key    string
+		labels Labels
+		want   time.Duration
+	}{
+		{key: "cpu.percent.thread", labels: Labels{"index": "0"}, want: 12 * time.Hour},
+		{key: "cpu.percent.thread", labels: Labels{"index": "2"}, want: 24 * time.Hour},
+		{key: "gpu.thread", labels: Labels{"index": "1"}, want: 48 * time.Hour},
+		{key: "cpu.percent", want: 7 * 24 * time.Hour},
+		{key: "disk.percent", labels: Labels{"mountpoint": "/home"}, want: time.Hour},
+		{key: "disk.percent", labels: Labels{"mountpoint": "/"}, want: 90 * 24 * time.Hour},
+		{key: "net.bytes_recv", labels: Labels{"interface": "eth0"}, want: 2 * time.Hour},
+		{key: "load.1", want: 90 * 24 * time.Hour},
+		{key: "host.uptime", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := r.For(tt.key, tt.labels); got != tt.want {
+			t.Errorf("For(%s%s) = %s, want %s", tt.key, tt.labels, got, tt.want)
+		}
+	}
+
+	if got := newRetention(t, conf.Retention{Default: conf.Duration(time.Hour)}).For("load.1", nil); got != time.Hour {
+		t.Errorf("For(load.1) = %s without rules, want the default", got)
+	}
+}
+
+func TestNewRetentionKeys(t *testing.T) {
+	for _, pattern := range []string{`{index="0"}`, `cpu.percent{index=0}`, `cpu.percent{index="0"`, "cpu percent"} {
+		_, err := NewRetention(conf.Retention{Keys: map[string]conf.Duration{pattern: conf.Duration(time.Hour)}})
+		if !errors.Is(err, ErrInvalidConfigError) {
+			t.Errorf("NewRetention(%s) error = %v, want %v", pattern, err, ErrInvalidConfigError)
+		}
+	}
+}
+
+func TestNewRetentionRollups(t *testing.T) {
+	for _, res := range []string{"5m", "1d", "minute"} {
+		_, err := NewRetention(conf.Retention{Rollups: map[string]conf.Duration{res: conf.Duration(time.Hour)}})
+		if !errors.Is(err, ErrInvalidConfigError) {
+			t.Errorf("NewRetention(%s rollups) error = %v, want %v", res, err, ErrInvalidConfigError)
+		}
+	}
+
+	r := newRetention(t, conf.Retention{Rollups: map[string]conf.Duration{"1m": 1, "1h": 2}})
+	if r.rollups[time.Minute] != 1 || r.rollups[time.Hour] != 2 || r.batch != defaultPruneBatch {
+		t.Errorf("NewRetention() = %+v", r)
+	}
+}
+
+func count(t *testing.T, conn *sql.DB, query string) int {
+	t.Helper()
+
+	var n int
+	if err := conn.QueryRow(query).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestPrune(t *testing.T) {
+	s, conn := newTestService(t)
+	now := time.Now()
+	old := now.Add(-2 * time.Hour).Format(time.DateTime)
+	recent := now.Add(-time.Minute).Format(time.DateTime)
+
+	execAll(t, conn,
+		`INSERT INTO series (id, key, labels, type) VALUES
+			(1, 'load.1', '', 'int'), (2, 'cpu.percent.thread', '{index="0"}', 'int'), (3, 'host.uptime', '', 'int')`)
+
+	// seven values to prune in batches of three
+	for range 7 {
+		execAll(t, conn, fmt.Sprintf(`INSERT INTO metric (series_id, int_value, date) VALUES (1, 1, '%s')`, old))
+	}
+
+	execAll(t, conn,
+		fmt.Sprintf(`INSERT INTO metric (series_id, int_value, date) VALUES (1, 2, '%s'), (2, 3, '%s'), (3, 4, '%s')`,
+			recent, old, old),
+		fmt.Sprintf(`INSERT INTO rollup VALUES (1, 60, %d, 1, 1, 1, 1), (1, 60, %d, 1, 1, 1, 1), (1, 60, %d, 1, 1, 1, 1)`,
+			wallUnix(now.Add(-3*time.Hour)), wallUnix(now.Add(-2*time.Hour)), wallUnix(now)),
+		fmt.Sprintf(`INSERT INTO rollup VALUES (1, 3600, %d, 1, 1, 1, 1)`, wallUnix(now.Add(-3*time.Hour))))
+
+	r := newRetention(t, conf.Retention{
+		Keys: map[string]conf.Duration{
+			"*":                  conf.Duration(time.Hour),
+			"cpu.percent.thread": conf.Duration(24 * time.Hour),
+			"host.uptime":        0,
+		},
+		Rollups: map[string]conf.Duration{"1m": conf.Duration(time.Hour), "1h": 0},
+		Batch:   3,
+	})
+
+	n, err := s.Prune(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 9 {
+		t.Errorf("Prune() removed %d rows, want 9", n)
+	}
+
+	if got := count(t, conn, `SELECT COUNT(*) FROM metric`); got != 3 {
+		t.Errorf("%d values left, want 3", got)
+	}
+
+	if got := count(t, conn, `SELECT COUNT(*) FROM metric WHERE series_id = 1`); got != 1 {
+		t.Errorf("%d load.1 values left, want the recent one", got)
+	}
+
+	if got := count(t, conn, `SELECT COUNT(*) FROM rollup WHERE resolution = 60`); got != 1 {
+		t.Errorf("%d minute rollups left, want 1", got)
+	}
+
+	if got := count(t, conn, `SELECT COUNT(*) FROM rollup WHERE resolution = 3600`); got != 1 {
+		t.Errorf("%d hour rollups left, want 1 kept forever", got)
+	}
+}
+
+func TestPruneMigratedKeys(t *testing.T) {
+	s, conn := newTestService(t)
+	s.metrics = []*Metric{internalMetric("cpu.percent.thread"), internalMetric("disk.percent./")}
+
+	old := time.Now().Add(-2 * time.Hour).Format(time.DateTime)
+
+	execAll(t, conn,
+		`INSERT INTO series (id, key, type) VALUES
+			(1, 'cpu.percent.thread.0', 'real'), (2, 'cpu.percent.thread.1', 'real'), (3, 'disk.percent./', 'real')`,
+		fmt.Sprintf(`INSERT INTO metric (series_id, real_value, date) VALUES (1, 1, '%[1]s'), (2, 2, '%[1]s'), (3, 3, '%[1]s')`, old))
+
+	if err := s.MigrateKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRetention(t, conf.Retention{
+		Keys: map[string]conf.Duration{
+			"cpu.percent.thread.*":          conf.Duration(time.Hour),
+			`cpu.percent.thread{index="1"}`: 0,
+			"disk.percent./":                conf.Duration(time.Hour),
+		},
+	})
+
+	n, err := s.Prune(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Errorf("Prune() removed %d rows, want 2", n)
+	}
+
+	if got := count(t, conn, `SELECT COUNT(*) FROM series WHERE labels = ''`); got != 0 {
+		t.Errorf("%d series left without labels, want all migrated", got)
+	}
+
+	left := count(t, conn, `SELECT COUNT(*) FROM metric
+		JOIN series ON series.id = metric.series_id
+		WHERE series.key = 'cpu.percent.thread' AND series.labels = '{index="1"}'`)
+	if left != 1 {
+		t.Errorf("%d values of thread 1 left, want 1 kept forever", left)
+	}
+}
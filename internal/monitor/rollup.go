@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/db/generated"
+)
+
+const (
+	// minChartPoints is the least number of points per series
+	// a rollup must give for the requested range to be used.
+	minChartPoints = 100
+	rawChunk       = time.Hour
+	rollupChunk    = 24 * time.Hour
+)
+
+// rollupLevels returns rollup resolutions from the finest. The first
+// one is built from raw values, the others from the previous level.
+func rollupLevels() []time.Duration {
+	return []time.Duration{time.Minute, time.Hour}
+}
+
+// wallUnix returns t's wall clock as Unix seconds. Values are stored
+// with a local wall clock date and read back as UTC, so buckets and
+// ranges are compared in the same terms.
+func wallUnix(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC).Unix()
+}
+
+func fromWallUnix(sec int64) time.Time {
+	return time.Unix(sec, 0).UTC()
+}
+
+// pickResolution returns the coarsest rollup resolution still giving
+// enough points for the span, or zero if raw values should be used.
+func pickResolution(span time.Duration) time.Duration {
+	levels := rollupLevels()
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		if span/levels[i] >= minChartPoints {
+			return levels[i]
+		}
+	}
+
+	return 0
+}
+
+func numeric(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, !math.IsNaN(n) && !math.IsInf(n, 0)
+	}
+
+	return 0, false
+}
+
+type bucketKey struct {
+	key    string
+	labels string
+	bucket int64
+}
+
+func (s *Service) rollupRaw(ctx context.Context, step int64, now int64) error {
+	_, from, err := s.repo.RollupRange(ctx, step)
+	if err != nil {
+		return err
+	}
+
+	if from > 0 {
+		from += step
+	} else {
+		oldest, ok, err := s.repo.OldestDate(ctx)
+		if err != nil || !ok {
+			return err
+		}
+
+		from = oldest.Unix() / step * step
+	}
+
+	end := now / step * step
+	chunk := int64(rawChunk / time.Second)
+
+	for ; from < end; from += chunk {
+		err := s.repo.RollupRaw(ctx, generated.RollupRawParams{
+			Resolution: step,
+			MinDate:    fromWallUnix(from),
+			MaxDate:    fromWallUnix(min(from+chunk, end)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) rollupLevel(ctx context.Context, step int64, source int64, now int64) error {
+	_, from, err := s.repo.RollupRange(ctx, step)
+	if err != nil {
+		return err
+	}
+
+	if from > 0 {
+		from += step
+	} else {
+		// Nothing rolled up yet, start from the oldest source bucket.
+		oldest, _, err := s.repo.RollupRange(ctx, source)
+		if err != nil || oldest == 0 {
+			return err
+		}
+
+		from = oldest / step * step
+	}
+
+	end := now / step * step
+	chunk := int64(rollupChunk / time.Second)
+
+	for ; from < end; from += chunk {
+		err := s.repo.RollupFrom(ctx, generated.RollupFromParams{
+			Resolution: step,
+			Source:     source,
+			MinBucket:  from,
+			MaxBucket:  min(from+chunk, end),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rollup aggregates complete buckets of every rollup level
+// since the last run.
+func (s *Service) Rollup(ctx context.Context) error {
+	now := wallUnix(time.Now())
+	levels := rollupLevels()
+
+	if err := s.rollupRaw(ctx, int64(levels[0]/time.Second), now); err != nil {
+		return fmt.Errorf("failed to roll up raw values: %w", err)
+	}
+
+	for i := 1; i < len(levels); i++ {
+		step := int64(levels[i] / time.Second)
+		source := int64(levels[i-1] / time.Second)
+
+		if err := s.rollupLevel(ctx, step, source, now); err != nil {
+			return fmt.Errorf("failed to roll up %s: %w", levels[i], err)
+		}
+	}
+
+	return nil
+}
+
+// rollupSplit returns the wall clock second, aligned to step, from
+// which values newer than the rollups of the resolution are read raw.
+// It reports false if raw values cover the start of the range the
+// rollups do not, as after enabling rollups or with a shorter rollup
+// retention.
+func (s *Service) rollupSplit(ctx context.Context, resolution int64, step int64, minDate time.Time) (int64, bool, error) {
+	oldest, newest, err := s.repo.RollupRange(ctx, resolution)
+	if err != nil || newest == 0 {
+		return 0, false, err
+	}
+
+	if oldest > wallUnix(minDate) {
+		raw, ok, err := s.repo.OldestDate(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if ok && raw.Unix() < oldest {
+			return 0, false, nil
+		}
+	}
+
+	// buckets are rolled up once complete
+	return (newest + resolution) / step * step, true, nil
+}
+
+// rollupMetric returns rollup averages of the resolution up to split
+// and averages of raw values in buckets of the resolution after it.
+func (s *Service) rollupMetric(
+	ctx context.Context,
+	resolution time.Duration,
+	split int64,
+	sel Selector,
+	minDate time.Time,
+	maxDate time.Time,
+	strict bool,
+) ([]Reading, error) {
+	rollups, err := s.repo.Rollup(ctx,
+		int64(resolution/time.Second), sel.Key, wallUnix(minDate), min(split, wallUnix(maxDate)), strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metric: %w", err)
+	}
+
+	readings := make([]Reading, 0, len(rollups))
+	labels := make(labelCache)
+
+	for _, r := range rollups {
+		l, err := labels.parse(r.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metric: %w", err)
+		}
+
+		if sel.MatchLabels(l) {
+			readings = append(readings, Reading{Key: r.Key, Labels: l, Value: r.Avg, Date: fromWallUnix(r.Bucket)})
+		}
+	}
+
+	if split >= wallUnix(maxDate) {
+		return readings, nil
+	}
+
+	// dates are compared after the second before split, fractions
+	// of it are dropped with their bucket
+	tail, err := s.aggregateRaw(ctx, sel, fromWallUnix(split-1), maxDate, strict, resolution, "avg")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range tail {
+		if wallUnix(r.Date) >= split {
+			readings = append(readings, r)
+		}
+	}
+
+	return readings, nil
+}
@@ -0,0 +1,166 @@
+package monitor
+
+import (
+	"context"
+	log "log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const (
+	defaultWorkers        = 4
+	defaultCollectTimeout = 30 * time.Second
+	defaultJitter         = 5 * time.Second
+	defaultInterval       = 5 * time.Second
+)
+
+// Scheduler collects every metric on its own timer, so a slow
+// collector delays neither the others nor its own schedule. At most
+// workers collections run at once, a metric still being collected
+// when due again is skipped. The timeout cancels the context of a
+// collection: reads of procfs and sysfs and system calls blocked in
+// the kernel, like statfs of a hung network mount, are not
+// interrupted and hold their worker until they return.
+type Scheduler struct {
+	svc     *Service
+	sem     chan struct{}
+	timeout time.Duration
+	jitter  time.Duration
+	running sync.WaitGroup
+}
+
+// Run collects metrics until the context is done and waits
+// for running collections to finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	var timers sync.WaitGroup
+
+	for _, m := range s.svc.metrics {
+		timers.Add(1)
+
+		go func() {
+			defer timers.Done()
+
+			s.schedule(ctx, m)
+		}()
+	}
+
+	timers.Wait()
+	s.running.Wait()
+}
+
+// offset is the random delay of the first collection.
+func (s *Scheduler) offset(interval time.Duration) time.Duration {
+	spread := min(interval, s.jitter)
+	if spread <= 0 {
+		return 0
+	}
+
+	return rand.N(spread)
+}
+
+func (s *Scheduler) schedule(ctx context.Context, m *Metric) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	next := time.Now().Add(s.offset(interval))
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.dispatch(ctx, m)
+
+		// next run is anchored to the schedule, not to the end
+		// of this one, so collection time doesn't drift it
+		next = next.Add(interval)
+		if now := time.Now(); next.Before(now) {
+			next = now
+		}
+
+		timer.Reset(time.Until(next))
+	}
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, m *Metric) {
+	if !m.running.CompareAndSwap(false, true) {
+		skipped := m.skipped.Add(1)
+
+		log.WarnContext(
+			ctx,
+			"skipping collection, previous one still running",
+			log.String("metric", m.Key),
+			log.Int64("skipped", skipped),
+		)
+
+		return
+	}
+
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+		defer m.running.Store(false)
+
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		defer func() { <-s.sem }()
+
+		s.collect(ctx, m)
+	}()
+}
+
+func (s *Scheduler) collect(ctx context.Context, m *Metric) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.DebugContext(ctx, "collect", log.String("key", m.Key))
+
+	if err := s.svc.collectMetric(ctx, m); err != nil {
+		log.ErrorContext(
+			ctx,
+			"failed to collect metric",
+			log.String("metric", m.Key),
+			log.String("type", m.Type),
+			log.Any("error", err),
+		)
+	}
+}
+
+func NewScheduler(svc *Service, cfg conf.Scheduler) *Scheduler {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = defaultCollectTimeout
+	}
+
+	jitter := time.Duration(cfg.Jitter)
+	if jitter <= 0 {
+		jitter = defaultJitter
+	}
+
+	return &Scheduler{
+		svc:     svc,
+		sem:     make(chan struct{}, workers),
+		timeout: timeout,
+		jitter:  jitter,
+	}
+}
@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// blockingMetric returns a metric whose collections signal started
+// and wait for release.
+func blockingMetric(key string, started chan<- string, release <-chan struct{}) *Metric {
+	return &Metric{
+		Key:      key,
+		Interval: time.Hour,
+		HandlerFunc: func(context.Context, *Metric) error {
+			started <- key
+			<-release
+
+			return nil
+		},
+	}
+}
+
+func newTestScheduler(cfg conf.Scheduler, metrics ...*Metric) *Scheduler {
+	return NewScheduler(&Service{metrics: metrics}, cfg)
+}
+
+func TestSchedulerSkipsOverlap(t *testing.T) {
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	m := blockingMetric("slow", started, release)
+	s := newTestScheduler(conf.Scheduler{}, m)
+
+	s.dispatch(context.Background(), m)
+	<-started
+
+	s.dispatch(context.Background(), m)
+	s.dispatch(context.Background(), m)
+
+	if m.Skipped() != 2 {
+		t.Fatalf("got %d skipped collections, want 2", m.Skipped())
+	}
+
+	close(release)
+	s.running.Wait()
+
+	if m.running.Load() {
+		t.Fatal("metric still running after its collection")
+	}
+
+	s.dispatch(context.Background(), m)
+	<-started
+	s.running.Wait()
+
+	if m.Skipped() != 2 || m.LastCheck.IsZero() {
+		t.Fatalf("got %d skipped, last check %s", m.Skipped(), m.LastCheck)
+	}
+}
+
+func TestSchedulerWorkers(t *testing.T) {
+	const workers = 2
+
+	started := make(chan string, 5)
+	release := make(chan struct{})
+
+	var metrics []*Metric
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		metrics = append(metrics, blockingMetric(key, started, release))
+	}
+
+	s := newTestScheduler(conf.Scheduler{Workers: workers}, metrics...)
+
+	for _, m := range metrics {
+		s.dispatch(context.Background(), m)
+	}
+
+	for range workers {
+		<-started
+	}
+
+	select {
+	case key := <-started:
+		t.Fatalf("%s collected while %d collections run", key, workers)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	s.running.Wait()
+
+	if n := len(started); n != len(metrics)-workers {
+		t.Fatalf("%d queued collections ran, want %d", n, len(metrics)-workers)
+	}
+}
+
+func TestSchedulerOffset(t *testing.T) {
+	s := newTestScheduler(conf.Scheduler{Jitter: conf.Duration(time.Second)})
+
+	for _, interval := range []time.Duration{100 * time.Millisecond, time.Minute} {
+		spread := min(interval, time.Second)
+
+		for range 1000 {
+			if d := s.offset(interval); d < 0 || d >= spread {
+				t.Fatalf("offset of %s interval is %s, want [0, %s)", interval, d, spread)
+			}
+		}
+	}
+}
+
+func TestSchedulerTimeout(t *testing.T) {
+	errs := make(chan error, 1)
+	m := &Metric{Key: "hung", HandlerFunc: func(ctx context.Context, _ *Metric) error {
+		<-ctx.Done()
+		errs <- ctx.Err()
+
+		return ctx.Err()
+	}}
+
+	s := newTestScheduler(conf.Scheduler{Timeout: conf.Duration(20 * time.Millisecond)}, m)
+	s.dispatch(context.Background(), m)
+	s.running.Wait()
+
+	if err := <-errs; !errors.Is(err, context.DeadlineExceeded) || m.Failures != 1 {
+		t.Fatalf("got %v after %d failures", err, m.Failures)
+	}
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	started := make(chan string, 1)
+	release := make(chan struct{})
+
+	var finished atomic.Bool
+
+	m := blockingMetric("slow", started, release)
+	handler := m.HandlerFunc
+	m.HandlerFunc = func(ctx context.Context, m *Metric) error {
+		defer finished.Store(true)
+
+		return handler(ctx, m)
+	}
+
+	s := newTestScheduler(conf.Scheduler{Jitter: conf.Duration(time.Millisecond)}, m)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+		t.Fatal("Run returned before the running collection finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after the collection finished")
+	}
+
+	if !finished.Load() {
+		t.Fatal("collection did not finish")
+	}
+}
@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSysRoot = "/sys"
+	milli          = 1000
+)
+
+var sensorInputRe = regexp.MustCompile(`^(temp|fan)(\d+)_input$`)
+
+func readTrimmed(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// sensorName turns a chip name or label like "Core 0" into a key
+// component like "core0".
+func sensorName(s string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// uniqueName returns name, or name suffixed with the device index
+// if the name was already taken by another device.
+func uniqueName(seen map[string]bool, name string, device string) string {
+	if seen[name] {
+		name = name + "_" + strings.TrimLeft(device, "abcdefghijklmnopqrstuvwxyz_")
+	}
+
+	seen[name] = true
+
+	return name
+}
+
+func readHwmon(root string, sensors map[string]float64) error {
+	devices, err := filepath.Glob(filepath.Join(root, "class", "hwmon", "hwmon*"))
+	if err != nil {
+		return fmt.Errorf("failed to list hwmon devices: %w", err)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, dev := range devices {
+		name, err := readTrimmed(filepath.Join(dev, "name"))
+		if err != nil {
+			continue
+		}
+
+		chip := uniqueName(seen, sensorName(name), filepath.Base(dev))
+
+		entries, err := os.ReadDir(dev)
+		if err != nil {
+			return fmt.Errorf("failed to read hwmon device: %w", err)
+		}
+
+		for _, e := range entries {
+			m := sensorInputRe.FindStringSubmatch(e.Name())
+			if m == nil {
+				continue
+			}
+
+			raw, err := readTrimmed(filepath.Join(dev, e.Name()))
+			if err != nil {
+				continue
+			}
+
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+
+			if m[1] == "temp" {
+				v /= milli
+			}
+
+			label := m[1] + m[2]
+			if l, err := readTrimmed(filepath.Join(dev, m[1]+m[2]+"_label")); err == nil && sensorName(l) != "" {
+				label = sensorName(l)
+			}
+
+			sensors[m[1]+"."+chip+"."+label] = v
+		}
+	}
+
+	return nil
+}
+
+func readThermal(root string, sensors map[string]float64) error {
+	zones, err := filepath.Glob(filepath.Join(root, "class", "thermal", "thermal_zone*"))
+	if err != nil {
+		return fmt.Errorf("failed to list thermal zones: %w", err)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, zone := range zones {
+		raw, err := readTrimmed(filepath.Join(zone, "temp"))
+		if err != nil {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			continue
+		}
+
+		name := filepath.Base(zone)
+		if t, err := readTrimmed(filepath.Join(zone, "type")); err == nil && sensorName(t) != "" {
+			name = sensorName(t)
+		}
+
+		sensors["temp.thermal."+uniqueName(seen, name, filepath.Base(zone))] = v / milli
+	}
+
+	return nil
+}
+
+// ReadSensors collects hardware sensors under the sysfs root.
+// Temperatures are in degrees Celsius, fans in RPM. The result is
+// keyed by <temp|fan>.<chip>.<label>, thermal zones are reported
+// as temp.thermal.<type>.
+func ReadSensors(root string) (map[string]float64, error) {
+	sensors := make(map[string]float64)
+
+	if err := readHwmon(root, sensors); err != nil {
+		return nil, err
+	}
+
+	if err := readThermal(root, sensors); err != nil {
+		return nil, err
+	}
+
+	return sensors, nil
+}
+
+// SensorByKey returns the sensor matching the key exactly, or all
+// sensors under it if the key is a prefix (sensor, sensor.temp,
+// sensor.fan.nct6775).
+func SensorByKey(root string, key string) ([]Value, error) {
+	sensors, err := ReadSensors(root)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(sensors))
+	for name := range sensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var values []Value
+
+	for _, name := range names {
+		full := "sensor." + name
+
+		sub, ok := strings.CutPrefix(full, key)
+		if !ok || (sub != "" && sub[0] != '.') {
+			continue
+		}
+
+		b, err := Float64ToBytes(sensors[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert: %w", err)
+		}
+
+		values = append(values, Value{Name: strings.TrimPrefix(sub, "."), Type: "float", Data: b})
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, key)
+	}
+
+	return values, nil
+}
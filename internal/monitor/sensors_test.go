@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sysFixture builds a sysfs tree with the files relative to its root.
+func sysFixture(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func sensorsFixture(t *testing.T) string {
+	t.Helper()
+
+	return sysFixture(t, map[string]string{
+		"class/hwmon/hwmon0/name":          "coretemp",
+		"class/hwmon/hwmon0/temp1_input":   "45000",
+		"class/hwmon/hwmon0/temp1_label":   "Core 0",
+		"class/hwmon/hwmon0/temp1_max":     "100000",
+		"class/hwmon/hwmon1/name":          "coretemp",
+		"class/hwmon/hwmon1/temp1_input":   "50500",
+		"class/hwmon/hwmon2/name":          "nct6775",
+		"class/hwmon/hwmon2/fan2_input":    "1200",
+		"class/hwmon/hwmon2/fan3_input":    "broken",
+		"class/hwmon/hwmon3/temp1_input":   "10000",
+		"class/thermal/thermal_zone0/type": "x86_pkg_temp",
+		"class/thermal/thermal_zone0/temp": "47000",
+		"class/thermal/thermal_zone1/temp": "30000",
+	})
+}
+
+func TestReadSensors(t *testing.T) {
+	sensors, err := ReadSensors(sensorsFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{
+		"temp.coretemp.core0":        45,
+		"temp.coretemp_1.temp1":      50.5,
+		"fan.nct6775.fan2":           1200,
+		"temp.thermal.x86_pkg_temp":  47,
+		"temp.thermal.thermal_zone1": 30,
+	}
+
+	if !reflect.DeepEqual(sensors, want) {
+		t.Errorf("ReadSensors() = %v, want %v", sensors, want)
+	}
+}
+
+func TestSensorByKey(t *testing.T) {
+	root := sensorsFixture(t)
+
+	tests := []struct {
+		key   string
+		names []string
+	}{
+		{key: "sensor.temp.coretemp.core0", names: []string{""}},
+		{key: "sensor.fan", names: []string{"nct6775.fan2"}},
+		{key: "sensor.temp.coretemp", names: []string{"core0"}},
+		{key: "sensor.temp", names: []string{"coretemp.core0", "coretemp_1.temp1", "thermal.thermal_zone1", "thermal.x86_pkg_temp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			values, err := SensorByKey(root, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := make([]string, len(values))
+			for i, v := range values {
+				names[i] = v.Name
+			}
+
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("SensorByKey(%s) names = %q, want %q", tt.key, names, tt.names)
+			}
+		})
+	}
+
+	if _, err := SensorByKey(root, "sensor.temp.core"); !errors.Is(err, ErrUnknownKeyError) {
+		t.Errorf("SensorByKey(sensor.temp.core) error = %v, want %v", err, ErrUnknownKeyError)
+	}
+}
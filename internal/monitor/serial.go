@@ -7,11 +7,9 @@ import (
 	"math"
 )
 
+// IntToBytes encodes a signed integer, negative
+// ones in two's complement.
 func IntToBytes(i int) ([]byte, error) {
-	if i < 0 {
-		return nil, fmt.Errorf("%w: %d", ErrIntegerOutOfRange, i)
-	}
-
 	num := int64(i)
 	buf := new(bytes.Buffer)
 
@@ -22,6 +20,18 @@ func IntToBytes(i int) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Uint64ToBytes encodes an unsigned integer as a signed one,
+// saturating at math.MaxInt64.
+func Uint64ToBytes(u uint64) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	if err := binary.Write(buf, binary.LittleEndian, saturated(u)); err != nil {
+		return nil, fmt.Errorf("failed to convert integer to bytes: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
 func Float64ToBytes(f float64) ([]byte, error) {
 	bits := math.Float64bits(f)
 	buf := new(bytes.Buffer)
@@ -33,13 +43,23 @@ func Float64ToBytes(f float64) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// saturated converts u to a signed integer, all integers
+// are stored signed.
+func saturated(u uint64) int64 {
+	return int64(min(u, math.MaxInt64))
+}
+
 func BytesToInt(b []byte) (int, error) {
-	u := binary.LittleEndian.Uint64(b)
-	if u > math.MaxInt {
-		return 0, fmt.Errorf("%w: %d", ErrIntegerOutOfRange, u)
+	i := int64(binary.LittleEndian.Uint64(b))
+	if i > math.MaxInt || i < math.MinInt {
+		return 0, fmt.Errorf("%w: %d", ErrIntegerOutOfRange, i)
 	}
 
-	return int(u), nil
+	return int(i), nil
+}
+
+func BytesToUint64(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
 }
 
 func BytesToFloat64(b []byte) (float64, error) {
@@ -48,3 +68,23 @@ func BytesToFloat64(b []byte) (float64, error) {
 
 	return f, nil
 }
+
+func uint64Value(name string, u uint64) Value {
+	return Value{Name: name, Type: "int", Data: binary.LittleEndian.AppendUint64(nil, uint64(saturated(u)))}
+}
+
+func intValue(name string, i int) Value {
+	return Value{Name: name, Type: "int", Data: binary.LittleEndian.AppendUint64(nil, uint64(int64(i)))}
+}
+
+func boolValue(name string, b bool) Value {
+	if b {
+		return intValue(name, 1)
+	}
+
+	return intValue(name, 0)
+}
+
+func floatValue(name string, f float64) Value {
+	return Value{Name: name, Type: "float", Data: binary.LittleEndian.AppendUint64(nil, math.Float64bits(f))}
+}
@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntRoundTrip(t *testing.T) {
+	tests := []struct {
+		u    uint64
+		want int
+	}{
+		{u: 0, want: 0},
+		{u: 1 << 40, want: 1 << 40},
+		{u: math.MaxInt64, want: math.MaxInt64},
+		{u: math.MaxInt64 + 1, want: math.MaxInt64},
+		{u: math.MaxUint64, want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		b, err := Uint64ToBytes(tt.u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, v := range []Value{{Data: b}, uint64Value("", tt.u)} {
+			if got := decoded(t, v, "int"); got != tt.want {
+				t.Errorf("%d decoded as %v, want %d", tt.u, got, tt.want)
+			}
+		}
+	}
+
+	for _, i := range []int{math.MinInt64, -1, 0, math.MaxInt64} {
+		b, err := IntToBytes(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, v := range []Value{{Data: b}, intValue("", i)} {
+			if got := decoded(t, v, "int"); got != i {
+				t.Errorf("%d decoded as %v", i, got)
+			}
+		}
+	}
+}
@@ -2,20 +2,32 @@ package monitor
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	log "log/slog"
-	"strings"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kirill-shtrykov/minimon/internal/conf"
 	"github.com/kirill-shtrykov/minimon/internal/db"
 	"github.com/kirill-shtrykov/minimon/internal/db/generated"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
 )
 
 type Reading struct {
-	Key   string
-	Value any
-	Date  time.Time
+	Key    string
+	Labels Labels
+	Value  any
+	Date   time.Time
+}
+
+// Series returns the key with labels identifying the series.
+func (r Reading) Series() string {
+	return r.Key + r.Labels.String()
 }
 
 func fromBytes(data []byte, to string) (any, error) {
@@ -31,150 +43,410 @@ func fromBytes(data []byte, to string) (any, error) {
 	return nil, fmt.Errorf("%w: %s", ErrUnknownValueType, to)
 }
 
+// Value is a single collected value of a metric. A non-empty Name is
+// appended to the metric key on store, a non-empty Type overrides
+// the metric type. Labels are added to the labels of the metric.
+type Value struct {
+	Name   string
+	Type   string
+	Data   []byte
+	Labels Labels
+}
+
+// Metric is a configured metric. Values are stored under Series
+// with Labels, both given by the collector and usually the same
+// as Key and none.
 type Metric struct {
 	Key         string
+	Method      string
+	Series      string
+	Labels      Labels
 	Type        string
-	LastValue   [][]byte
+	Unit        string
+	LastValue   []Value
 	LastCheck   time.Time
 	Interval    time.Duration
-	HandlerFunc func(metric *Metric) error
+	Root        string
+	Failures    int
+	LastError   error
+	HandlerFunc func(ctx context.Context, metric *Metric) error
+
+	running atomic.Bool
+	skipped atomic.Int64
+}
+
+// Skipped returns how many collections were skipped because
+// the previous one was still running.
+func (m *Metric) Skipped() int64 {
+	return m.skipped.Load()
 }
 
-func (m *Metric) Handler() error {
-	if err := m.HandlerFunc(m); err != nil {
+func (m *Metric) Handler(ctx context.Context) error {
+	if err := m.HandlerFunc(ctx, m); err != nil {
+		m.Failures++
+		m.LastError = err
+
 		return fmt.Errorf("failed to make check: %w", err)
 	}
 
+	m.LastError = nil
 	m.LastCheck = time.Now()
 
 	return nil
 }
 
+// staleIntervals is how many collection intervals a latest value
+// is current for.
+const staleIntervals = 2
+
+// Latest is the most recently collected value of a metric.
+// Name is the value name within the metric, empty for
+// single valued metrics.
+type Latest struct {
+	Metric   string
+	Name     string
+	Labels   Labels
+	Type     string
+	Value    any
+	Date     time.Time
+	Interval time.Duration
+}
+
+// Key returns the stored key of the value.
+func (l Latest) Key() string {
+	if l.Name == "" {
+		return l.Metric
+	}
+
+	return l.Metric + "." + l.Name
+}
+
+// Series returns the key with labels identifying the series.
+func (l Latest) Series() string {
+	return l.Key() + l.Labels.String()
+}
+
+// Float returns the value as float64, false for strings
+// and values that are not finite.
+func (l Latest) Float() (float64, bool) {
+	return numeric(l.Value)
+}
+
+// StaleAfter returns how long the value is current, two collection
+// intervals, so one failed or late collection is tolerated.
+func (l Latest) StaleAfter() time.Duration {
+	if l.Interval <= 0 {
+		return staleIntervals * defaultInterval
+	}
+
+	return staleIntervals * l.Interval
+}
+
+// Stale reports whether the value is no longer current,
+// as when its collection keeps failing.
+func (l Latest) Stale(now time.Time) bool {
+	return now.Sub(l.Date) > l.StaleAfter()
+}
+
 type Service struct {
 	repo    *db.Repo
 	metrics []*Metric
+	mu      sync.RWMutex
+	latest  map[string]Latest
+	series  map[string]int64
+	rollups bool
+	broker  *Broker
 }
 
-func (s *Service) CollectAndStore(ctx context.Context) {
-	for _, m := range s.metrics {
-		if time.Since(m.LastCheck) >= m.Interval {
-			log.DebugContext(ctx, "collect", log.String("key", m.Key))
-
-			if err := s.collectMetric(ctx, m); err != nil {
-				log.ErrorContext(
-					ctx,
-					"failed to collect metric",
-					log.String("metric", m.Key),
-					log.String("type", m.Type),
-					log.Any("error", err),
-				)
-			}
-		}
-	}
+// EnableRollups makes long range queries read rollups
+// instead of raw values.
+func (s *Service) EnableRollups() {
+	s.rollups = true
 }
 
 func (s *Service) collectMetric(ctx context.Context, metric *Metric) error {
-	err := metric.Handler()
+	err := metric.Handler(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to run handler: %w", err)
 	}
 
-	if len(metric.LastValue) == 1 {
-		if err := s.store(ctx, metric.Key, metric.LastValue[0], metric.Type); err != nil {
-			return fmt.Errorf("failed to store metric: %w", err)
+	for _, v := range metric.LastValue {
+		l := Latest{
+			Metric:   metric.Series,
+			Name:     v.Name,
+			Labels:   metric.Labels.With(v.Labels),
+			Type:     metric.Type,
+			Date:     metric.LastCheck,
+			Interval: metric.Interval,
 		}
-	} else {
-		for i, v := range metric.LastValue {
-			if err := s.store(ctx, fmt.Sprintf("%s.%d", metric.Key, i), v, metric.Type); err != nil {
-				return fmt.Errorf("failed to store metric: %w", err)
-			}
+
+		if v.Type != "" {
+			l.Type = v.Type
+		}
+
+		l.Value, err = fromBytes(v.Data, l.Type)
+		if err != nil {
+			log.WarnContext(ctx, "failed to decode value", log.String("series", l.Series()), log.Any("error", err))
+
+			continue
+		}
+
+		if err := s.store(ctx, l, metric.Unit); err != nil {
+			return fmt.Errorf("failed to store metric: %w", err)
 		}
+
+		s.mu.Lock()
+		s.latest[l.Series()] = l
+		s.mu.Unlock()
+
+		// dated the way stored values read back
+		s.broker.Publish(ctx, Reading{
+			Key:    l.Key(),
+			Labels: l.Labels,
+			Value:  l.Value,
+			Date:   fromWallUnix(wallUnix(metric.LastCheck)),
+		})
 	}
 
 	return nil
 }
 
-func (s *Service) store(ctx context.Context, key string, value []byte, t string) error {
-	err := s.repo.AddValue(ctx, generated.AddValueParams{Key: key, Type: t, Value: value})
+// Subscribe streams readings as they are collected, see Broker.Subscribe.
+func (s *Service) Subscribe(sel Selector, strict bool) (<-chan Reading, func()) {
+	return s.broker.Subscribe(sel, strict)
+}
+
+// Latest returns the most recent value of every collected key
+// ordered by key.
+func (s *Service) Latest() []Latest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.latest))
+	for k := range s.latest {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	latest := make([]Latest, len(keys))
+	for i, k := range keys {
+		latest[i] = s.latest[k]
+	}
+
+	return latest
+}
+
+// seriesID returns the ID of the series of the value, IDs are
+// cached for the lifetime of the service.
+func (s *Service) seriesID(ctx context.Context, l Latest, unit string) (int64, error) {
+	series := l.Series()
+
+	s.mu.RLock()
+	id, ok := s.series[series]
+	s.mu.RUnlock()
+
+	if ok {
+		return id, nil
+	}
+
+	id, err := s.repo.SeriesID(ctx, generated.UpsertSeriesParams{
+		Key:    l.Key(),
+		Labels: l.Labels.String(),
+		Type:   l.Type,
+		Unit:   unit,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.series[series] = id
+	s.mu.Unlock()
+
+	return id, nil
+}
+
+// store stores the value in the column of its type, floats that
+// are not finite are stored as NULL.
+func (s *Service) store(ctx context.Context, l Latest, unit string) error {
+	id, err := s.seriesID(ctx, l, unit)
 	if err != nil {
 		return fmt.Errorf("failed to store metric: %w", err)
 	}
 
+	arg := generated.AddValueParams{SeriesID: id}
+
+	switch v := l.Value.(type) {
+	case int:
+		arg.IntValue = sql.NullInt64{Int64: int64(v), Valid: true}
+	case float64:
+		arg.RealValue = sql.NullFloat64{Float64: v, Valid: !math.IsNaN(v) && !math.IsInf(v, 0)}
+	case string:
+		arg.TextValue = sql.NullString{String: v, Valid: true}
+	}
+
+	if err := s.repo.AddValue(ctx, arg); err != nil {
+		return fmt.Errorf("failed to store metric: %w", err)
+	}
+
 	return nil
 }
 
-func CollectInternal(m *Metric) error {
-	switch k := strings.Split(m.Key, "."); k[0] {
-	case "cpu":
-		v, err := CPUByKey(m.Key)
-		if err != nil {
-			return err
-		}
-
-		m.LastValue = v
-	default:
-		return fmt.Errorf("%w: %s", ErrUnknownKeyError, m.Key)
+// rowValue returns the value of the column it is stored in,
+// nil if there is none.
+func rowValue(i sql.NullInt64, f sql.NullFloat64, t sql.NullString) any {
+	switch {
+	case i.Valid:
+		return int(i.Int64)
+	case f.Valid:
+		return f.Float64
+	case t.Valid:
+		return t.String
 	}
 
 	return nil
 }
 
+// indexed wraps raw collector output into values. Multiple values
+// are told apart by the index label.
+func indexed(bs [][]byte) []Value {
+	values := make([]Value, len(bs))
+
+	if len(bs) == 1 {
+		values[0] = Value{Data: bs[0]}
+
+		return values
+	}
+
+	for i, b := range bs {
+		values[i] = Value{Data: b, Labels: Labels{indexLabel: strconv.Itoa(i)}}
+	}
+
+	return values
+}
+
+func rootOr(root string, def string) string {
+	if root == "" {
+		return def
+	}
+
+	return root
+}
+
+// Metric returns readings of the series selected by sel, see
+// Selector.Matches.
 func (s *Service) Metric(
 	ctx context.Context,
-	key string,
+	sel Selector,
 	minDate time.Time,
 	maxDate time.Time,
 	strict bool,
 ) ([]Reading, error) {
-	var metrics []generated.Metric
+	if s.rollups {
+		if resolution := pickResolution(maxDate.Sub(minDate)); resolution > 0 {
+			res := int64(resolution / time.Second)
+
+			split, ok, err := s.rollupSplit(ctx, res, res, minDate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get metric: %w", err)
+			}
+
+			if ok {
+				return s.rollupMetric(ctx, resolution, split, sel, minDate, maxDate, strict)
+			}
+		}
+	}
+
+	return s.rawMetric(ctx, sel, minDate, maxDate, strict)
+}
+
+func (s *Service) rawMetric(
+	ctx context.Context,
+	sel Selector,
+	minDate time.Time,
+	maxDate time.Time,
+	strict bool,
+) ([]Reading, error) {
+	var metrics []generated.MetricRow
 	var err error
 
-	if key == "" {
+	if sel.Key == "" {
 		metrics, err = s.repo.MetricsByDate(ctx, minDate, maxDate)
 	} else {
-		metrics, err = s.repo.Metric(ctx, key, minDate, maxDate, strict)
+		metrics, err = s.repo.Metric(ctx, sel.Key, minDate, maxDate, strict)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get metric: %w", err)
 	}
 
-	readings := make([]Reading, len(metrics))
+	readings := make([]Reading, 0, len(metrics))
+	labels := make(labelCache)
 
-	for i, m := range metrics {
-		value, err := fromBytes(m.Value, m.Type)
+	for _, m := range metrics {
+		l, err := labels.parse(m.Labels)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get metric: %w", err)
 		}
 
-		readings[i] = Reading{Key: m.Key, Value: value, Date: m.Date}
+		if sel.MatchLabels(l) {
+			readings = append(readings, Reading{
+				Key:    m.Key,
+				Labels: l,
+				Value:  rowValue(m.IntValue, m.RealValue, m.TextValue),
+				Date:   m.Date,
+			})
+		}
 	}
 
 	return readings, nil
 }
 
-func New(repo *db.Repo, cfg []conf.Metric) (*Service, error) {
+func New(repo *db.Repo, reg *collector.Registry, cfg []conf.Metric) (*Service, error) {
 	metrics := make([]*Metric, len(cfg))
 
 	for i, m := range cfg {
-		var h func(metric *Metric) error
+		c, err := reg.New(collector.Config{
+			Key:    m.Key,
+			Method: m.Method,
+			Type:   m.Type,
+			Root:   m.Root,
+			Decode: m.Decode,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: %w", m.Key, err)
+		}
+
+		desc := c.Describe()
+		if desc.Key == "" {
+			desc.Key = m.Key
+		}
 
-		switch m.Method {
-		case "internal":
-			h = CollectInternal
-		default:
-			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMetricMethodError, m.Method)
+		if m.Unit != "" {
+			desc.Unit = m.Unit
 		}
 
-		metrics[i] = &Metric{
-			Key:         m.Key,
-			Type:        m.Type,
-			LastValue:   nil,
-			LastCheck:   time.Time{},
-			Interval:    time.Duration(m.Interval) * time.Second,
-			HandlerFunc: h,
+		metric := &Metric{Key: m.Key, Type: m.Type, Root: m.Root, HandlerFunc: collectorHandler(c)}
+
+		// built-in collectors run on their metric without
+		// encoding values again
+		if b, ok := c.(*builtin); ok {
+			metric = b.metric
 		}
+
+		metric.Method = m.Method
+		metric.Series = desc.Key
+		metric.Labels = desc.Labels
+		metric.Unit = desc.Unit
+		metric.Interval = time.Duration(m.Interval) * time.Second
+		metrics[i] = metric
 	}
 
-	return &Service{repo: repo, metrics: metrics}, nil
+	return &Service{
+		repo:    repo,
+		metrics: metrics,
+		latest:  make(map[string]Latest),
+		series:  make(map[string]int64),
+		broker:  NewBroker(defaultSubscriberBuffer),
+	}, nil
 }
@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Register sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+)
+
+// testBase returns the wall clock the stored test values start at.
+func testBase() time.Time {
+	return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// newTestService returns a service with rollups enabled on an empty
+// database and a connection to run fixture statements on.
+func newTestService(t *testing.T) (*Service, *sql.DB) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "minimon.db")
+
+	schema, err := os.ReadFile("../db/queries/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err := conn.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := db.New(conf.SQLiteConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(repo, collector.NewRegistry(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.EnableRollups()
+
+	return s, conn
+}
+
+func execAll(t *testing.T, conn *sql.DB, queries ...string) {
+	t.Helper()
+
+	for _, q := range queries {
+		if _, err := conn.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+}
+
+// seedMinutes stores two raw values in every minute of [from, to)
+// minutes after base, 10 and 20 at the start of the minute.
+func seedMinutes(t *testing.T, conn *sql.DB, from int, to int) {
+	t.Helper()
+
+	base := testBase()
+
+	for i := from; i < to; i++ {
+		date := base.Add(time.Duration(i) * time.Minute).Format(time.DateTime)
+		execAll(t, conn, fmt.Sprintf(
+			`INSERT INTO metric (series_id, int_value, date) VALUES (1, 10, '%s'), (1, 20, '%s')`, date, date))
+	}
+}
+
+// seedRollups stores minute rollups of [from, to) minutes after base.
+func seedRollups(t *testing.T, conn *sql.DB, from int, to int) {
+	t.Helper()
+
+	for i := from; i < to; i++ {
+		execAll(t, conn, fmt.Sprintf(
+			`INSERT INTO rollup VALUES (1, 60, %d, 1, 1, 1, 1)`, wallUnix(testBase())+int64(i)*60))
+	}
+}
+
+func TestMetricRollupTail(t *testing.T) {
+	s, conn := newTestService(t)
+	base := testBase()
+
+	execAll(t, conn, `INSERT INTO series (id, key, type) VALUES (1, 'load', 'int')`)
+	seedRollups(t, conn, 0, 120)
+	// values of the last complete minutes are not rolled up yet
+	seedMinutes(t, conn, 120, 130)
+
+	readings, err := s.Metric(context.Background(), Selector{Key: "load"}, base, base.Add(130*time.Minute), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(readings) != 130 {
+		t.Fatalf("got %d readings, want 130", len(readings))
+	}
+
+	for i, r := range readings {
+		want := 1.0
+		if i >= 120 {
+			want = 15
+		}
+
+		if !r.Date.Equal(base.Add(time.Duration(i)*time.Minute)) || r.Value != want {
+			t.Fatalf("reading %d = %v at %s, want %v at minute %d", i, r.Value, r.Date, want, i)
+		}
+	}
+}
+
+func TestMetricRollupCoverage(t *testing.T) {
+	s, conn := newTestService(t)
+	base := testBase()
+
+	execAll(t, conn, `INSERT INTO series (id, key, type) VALUES (1, 'load', 'int')`)
+	// rollups were enabled an hour after values were first stored
+	seedMinutes(t, conn, 0, 130)
+	seedRollups(t, conn, 60, 120)
+
+	readings, err := s.Metric(context.Background(), Selector{Key: "load"}, base, base.Add(130*time.Minute), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// raw values are read after minDate
+	if len(readings) != 258 {
+		t.Fatalf("got %d readings, want 258 raw values", len(readings))
+	}
+}
+
+func TestAggregateRollupTail(t *testing.T) {
+	s, conn := newTestService(t)
+	base := testBase()
+
+	execAll(t, conn, `INSERT INTO series (id, key, type) VALUES (1, 'load', 'int')`)
+	seedRollups(t, conn, 0, 120)
+	seedMinutes(t, conn, 120, 130)
+
+	readings, err := s.Aggregate(context.Background(), Selector{Key: "load"},
+		base, base.Add(130*time.Minute), true, 5*time.Minute, "avg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(readings) != 26 {
+		t.Fatalf("got %d readings, want 26", len(readings))
+	}
+
+	for i, r := range readings {
+		want := 1.0
+		if i >= 24 {
+			want = 15
+		}
+
+		if !r.Date.Equal(base.Add(time.Duration(i)*5*time.Minute)) || r.Value != want {
+			t.Fatalf("reading %d = %v at %s, want %v", i, r.Value, r.Date, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	log "log/slog"
+	"net"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const maxResponseSize = 4096
+
+// TCPCollector checks a TCP or UDP port reachability, optionally
+// sending a payload and expecting a response containing a pattern.
+type TCPCollector struct {
+	network string
+	address string
+	send    []byte
+	expect  []byte
+	timeout time.Duration
+}
+
+// readExpected reads from conn until the expected payload shows up,
+// the peer closes the connection or the deadline is reached.
+func (c *TCPCollector) readExpected(conn net.Conn) bool {
+	buf := make([]byte, 0, maxResponseSize)
+	chunk := make([]byte, maxResponseSize)
+
+	for len(buf) < maxResponseSize {
+		n, err := conn.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+
+		if len(c.expect) == 0 && n > 0 {
+			return true
+		}
+
+		if len(c.expect) > 0 && bytes.Contains(buf, c.expect) {
+			return true
+		}
+
+		if err != nil {
+			return false
+		}
+	}
+
+	return false
+}
+
+func (c *TCPCollector) probe(ctx context.Context) (bool, bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var d net.Dialer
+
+	start := time.Now()
+
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return false, false, 0, fmt.Errorf("failed to dial: %w", err)
+	}
+	defer conn.Close()
+
+	latency := time.Since(start)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return false, false, latency, fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+
+	if len(c.send) > 0 {
+		if _, err := conn.Write(c.send); err != nil {
+			return false, false, latency, fmt.Errorf("failed to send: %w", err)
+		}
+	}
+
+	// UDP is connectionless, so only a response proves the port is up
+	// and the round trip is the only meaningful latency.
+	if c.network == "udp" {
+		ok := c.readExpected(conn)
+
+		return ok, ok, time.Since(start), nil
+	}
+
+	if len(c.expect) == 0 {
+		return true, false, latency, nil
+	}
+
+	return true, c.readExpected(conn), latency, nil
+}
+
+func (c *TCPCollector) Collect(ctx context.Context, m *Metric) error {
+	up, match, latency, err := c.probe(ctx)
+	if err != nil {
+		log.DebugContext(ctx, "port probe failed", log.String("address", c.address), log.Any("error", err))
+	}
+
+	values := []Value{boolValue("up", up)}
+
+	if err == nil {
+		values = append(values, floatValue("latency_ms", millis(latency)))
+	}
+
+	if len(c.expect) > 0 {
+		values = append(values, boolValue("match", match))
+	}
+
+	m.LastValue = values
+
+	return nil
+}
+
+func NewTCPCollector(cfg conf.TCP) (*TCPCollector, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("%w: address is required", ErrInvalidConfigError)
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		return nil, fmt.Errorf("%w: address: %w", ErrInvalidConfigError, err)
+	}
+
+	c := &TCPCollector{
+		network: "tcp",
+		address: cfg.Address,
+		send:    []byte(cfg.Send),
+		expect:  []byte(cfg.Expect),
+		timeout: defaultProbeTimeout,
+	}
+
+	switch cfg.Network {
+	case "", "tcp":
+	case "udp":
+		if cfg.Send == "" {
+			return nil, fmt.Errorf("%w: udp check requires a payload to send", ErrInvalidConfigError)
+		}
+
+		c.network = "udp"
+	default:
+		return nil, fmt.Errorf("%w: unknown network %q", ErrInvalidConfigError, cfg.Network)
+	}
+
+	if cfg.Timeout > 0 {
+		c.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	return c, nil
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"net"
+	"testing"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// listenBanner accepts connections on a local port and greets
+// every client with banner.
+func listenBanner(t *testing.T, banner string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			_, _ = conn.Write([]byte(banner))
+			conn.Close()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// refusedAddress returns a local address nothing listens on.
+func refusedAddress(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+
+	return addr
+}
+
+func newTCPCollector(t *testing.T, cfg conf.TCP) *TCPCollector {
+	t.Helper()
+
+	c, err := NewTCPCollector(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestTCPCollect(t *testing.T) {
+	addr := listenBanner(t, "SSH-2.0-OpenSSH_9.6\r\n")
+
+	tests := []struct {
+		name    string
+		cfg     conf.TCP
+		up      int
+		match   any
+		latency bool
+	}{
+		{name: "up", cfg: conf.TCP{Address: addr}, up: 1, latency: true},
+		{name: "expected", cfg: conf.TCP{Address: addr, Expect: "SSH-2.0"}, up: 1, match: 1, latency: true},
+		{name: "unexpected", cfg: conf.TCP{Address: addr, Expect: "220 "}, up: 1, match: 0, latency: true},
+		{name: "refused", cfg: conf.TCP{Address: refusedAddress(t)}, up: 0},
+		{name: "refused expected", cfg: conf.TCP{Address: refusedAddress(t), Expect: "SSH-2.0"}, up: 0, match: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := collectValues(t, newTCPCollector(t, tt.cfg).Collect)
+
+			if values["up"] != tt.up || values["match"] != tt.match {
+				t.Fatalf("got %v", values)
+			}
+
+			if _, ok := values["latency_ms"]; ok != tt.latency {
+				t.Fatalf("got %v, want latency %t", values, tt.latency)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// TLSCollector reports certificate expiry and chain validity of
+// a remote endpoint or a local PEM file, labelled with the issuer.
+type TLSCollector struct {
+	address    string
+	file       string
+	serverName string
+	startTLS   string
+	roots      *x509.CertPool
+	timeout    time.Duration
+}
+
+func readPEMCertificates(path string) ([]*x509.Certificate, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoCertificateError, path)
+	}
+
+	return certs, nil
+}
+
+// peerCertificates completes a TLS handshake with the endpoint and
+// returns the presented chain. Verification is done separately, so
+// an invalid chain can still be inspected.
+func (c *TLSCollector) peerCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+
+	cfg := &tls.Config{ServerName: c.serverName, InsecureSkipVerify: true}
+
+	if c.startTLS == "smtp" {
+		client, err := smtp.NewClient(conn, c.serverName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to greet SMTP server: %w", err)
+		}
+		defer client.Close()
+
+		if err := client.StartTLS(cfg); err != nil {
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+
+		state, _ := client.TLSConnectionState()
+
+		return state.PeerCertificates, nil
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to complete TLS handshake: %w", err)
+	}
+
+	return tlsConn.ConnectionState().PeerCertificates, nil
+}
+
+func (c *TLSCollector) certificates(ctx context.Context) ([]*x509.Certificate, error) {
+	if c.file != "" {
+		return readPEMCertificates(c.file)
+	}
+
+	certs, err := c.peerCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoCertificateError, c.address)
+	}
+
+	return certs, nil
+}
+
+// verify checks the leaf against system (or configured) roots
+// using the rest of the chain as intermediates. Remote certificates
+// are also checked to match the server name.
+func (c *TLSCollector) verify(certs []*x509.Certificate) bool {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{Roots: c.roots, Intermediates: intermediates}
+	if c.file == "" {
+		opts.DNSName = c.serverName
+	}
+
+	_, err := certs[0].Verify(opts)
+
+	return err == nil
+}
+
+func (c *TLSCollector) Collect(ctx context.Context, m *Metric) error {
+	certs, err := c.certificates(ctx)
+	if err != nil {
+		return err
+	}
+
+	leaf := certs[0]
+	chainExpiry := leaf.NotAfter
+
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(chainExpiry) {
+			chainExpiry = cert.NotAfter
+		}
+	}
+
+	values := []Value{
+		floatValue("expiry_days", daysUntil(leaf.NotAfter)),
+		floatValue("chain_expiry_days", daysUntil(chainExpiry)),
+		boolValue("valid", c.verify(certs)),
+	}
+
+	for i := range values {
+		values[i].Labels = Labels{"issuer": leaf.Issuer.String()}
+	}
+
+	m.LastValue = values
+
+	return nil
+}
+
+func NewTLSCollector(cfg conf.TLS) (*TLSCollector, error) {
+	if (cfg.Address == "") == (cfg.File == "") {
+		return nil, fmt.Errorf("%w: exactly one of address or file is required", ErrInvalidConfigError)
+	}
+
+	c := &TLSCollector{
+		address:    cfg.Address,
+		file:       cfg.File,
+		serverName: cfg.ServerName,
+		timeout:    defaultProbeTimeout,
+	}
+
+	if cfg.Address != "" && c.serverName == "" {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, fmt.Errorf("%w: address: %w", ErrInvalidConfigError, err)
+		}
+
+		c.serverName = host
+	}
+
+	if cfg.CAFile != "" {
+		cas, err := readPEMCertificates(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: ca_file: %w", ErrInvalidConfigError, err)
+		}
+
+		c.roots = x509.NewCertPool()
+		for _, ca := range cas {
+			c.roots.AddCert(ca)
+		}
+	}
+
+	switch cfg.StartTLS {
+	case "", "smtp":
+		c.startTLS = cfg.StartTLS
+	default:
+		return nil, fmt.Errorf("%w: unsupported starttls protocol %q", ErrInvalidConfigError, cfg.StartTLS)
+	}
+
+	if cfg.Timeout > 0 {
+		c.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	return c, nil
+}
@@ -0,0 +1,203 @@
+package monitor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// testCert is a certificate with its key, signed by parent
+// or self-signed if there is none.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, days int, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Duration(days) * 24 * time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	issuer, signer := tmpl, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key}
+}
+
+func writePEM(t *testing.T, certs ...*x509.Certificate) string {
+	t.Helper()
+
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// collectTLS returns the collected values by name
+// and the issuer they are labelled with.
+func collectTLS(t *testing.T, cfg conf.TLS) (map[string]any, string) {
+	t.Helper()
+
+	c, err := NewTLSCollector(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m Metric
+	if err := c.Collect(context.Background(), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]any, len(m.LastValue))
+	issuer := m.LastValue[0].Labels["issuer"]
+
+	for _, v := range m.LastValue {
+		if v.Type == "string" || v.Labels["issuer"] != issuer {
+			t.Fatalf("got %+v", v)
+		}
+
+		values[v.Name] = decoded(t, v, v.Type)
+	}
+
+	return values, issuer
+}
+
+func days(v any) int {
+	return int(math.Round(v.(float64)))
+}
+
+func TestTLSFile(t *testing.T) {
+	ca := newTestCert(t, "Test CA", 30, nil)
+	leaf := newTestCert(t, "web1", 90, ca)
+
+	tests := []struct {
+		name   string
+		cfg    conf.TLS
+		valid  int
+		issuer string
+		chain  int
+	}{
+		{
+			name:   "self-signed",
+			cfg:    conf.TLS{File: writePEM(t, ca.cert)},
+			issuer: "CN=Test CA",
+			chain:  30,
+		},
+		{
+			name:   "untrusted chain",
+			cfg:    conf.TLS{File: writePEM(t, leaf.cert, ca.cert)},
+			issuer: "CN=Test CA",
+			chain:  30,
+		},
+		{
+			name:   "trusted leaf",
+			cfg:    conf.TLS{File: writePEM(t, leaf.cert), CAFile: writePEM(t, ca.cert)},
+			valid:  1,
+			issuer: "CN=Test CA",
+			chain:  90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, issuer := collectTLS(t, tt.cfg)
+
+			if values["valid"] != tt.valid || issuer != tt.issuer || days(values["chain_expiry_days"]) != tt.chain {
+				t.Fatalf("got %v issued by %q", values, issuer)
+			}
+		})
+	}
+}
+
+func TestTLSNoCertificate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewTLSCollector(conf.TLS{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Collect(context.Background(), &Metric{}); !errors.Is(err, ErrNoCertificateError) {
+		t.Fatalf("got %v, want %v", err, ErrNoCertificateError)
+	}
+}
+
+func TestTLSAddress(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	t.Cleanup(srv.Close)
+
+	addr := srv.Listener.Addr().String()
+	cert := srv.Certificate()
+
+	values, _ := collectTLS(t, conf.TLS{Address: addr})
+	if values["valid"] != 0 {
+		t.Fatalf("got %v for an untrusted server", values)
+	}
+
+	values, _ = collectTLS(t, conf.TLS{Address: addr, CAFile: writePEM(t, cert)})
+	if values["valid"] != 1 || days(values["expiry_days"]) != days(daysUntil(cert.NotAfter)) {
+		t.Fatalf("got %v", values)
+	}
+}
+
+func TestNewTLSCollector(t *testing.T) {
+	for _, cfg := range []conf.TLS{
+		{},
+		{Address: "example.com:443", File: "/etc/ssl/cert.pem"},
+		{Address: "example.com"},
+		{Address: "example.com:25", StartTLS: "imap"},
+	} {
+		if _, err := NewTLSCollector(cfg); !errors.Is(err, ErrInvalidConfigError) {
+			t.Fatalf("%+v: got %v, want %v", cfg, err, ErrInvalidConfigError)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/alert"
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const (
+	defaultSMTPPort = 587
+	implicitTLSPort = 465
+
+	defaultSubject = `[minimon] {{.Host}}: {{len .Firing}} firing, {{len .Resolved}} resolved`
+	defaultBody    = `{{range .Notifications}}{{.State}}: {{.Rule}}
+  key:   {{.Key}}
+  value: {{.Value}}
+  at:    {{.At.Format "2006-01-02 15:04:05 MST"}}
+
+{{end}}`
+)
+
+// Mail is the data the subject and body templates are rendered with.
+type Mail struct {
+	Host          string
+	Notifications []Notification
+	Firing        []Notification
+	Resolved      []Notification
+}
+
+func newMail(notifications []Notification) Mail {
+	m := Mail{Notifications: notifications}
+
+	for _, n := range notifications {
+		m.Host = n.Host
+
+		switch alert.State(n.State) {
+		case alert.StateFiring:
+			m.Firing = append(m.Firing, n)
+		case alert.StateResolved:
+			m.Resolved = append(m.Resolved, n)
+		}
+	}
+
+	return m
+}
+
+// Email mails notifications over SMTP.
+type Email struct {
+	addr    string
+	host    string
+	auth    smtp.Auth
+	from    string
+	to      []string
+	mode    string
+	tls     *tls.Config
+	subject *template.Template
+	body    *template.Template
+	timeout time.Duration
+}
+
+func (e *Email) message(notifications []Notification) ([]byte, error) {
+	mail := newMail(notifications)
+
+	var subject, body bytes.Buffer
+
+	if err := e.subject.Execute(&subject, mail); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+
+	if err := e.body.Execute(&body, mail); err != nil {
+		return nil, fmt.Errorf("failed to render body: %w", err)
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func (e *Email) dial(ctx context.Context) (*smtp.Client, error) {
+	dialer := &net.Dialer{Timeout: e.timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	deadline := time.Now().Add(e.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	if e.mode == "tls" {
+		conn = tls.Client(conn, e.tls)
+	}
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	if e.mode == "tls" || e.mode == "none" {
+		return c, nil
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(e.tls); err != nil {
+			c.Close()
+
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	} else if e.mode == "starttls" {
+		c.Close()
+
+		return nil, ErrNoStartTLSError
+	}
+
+	return c, nil
+}
+
+func (e *Email) Send(ctx context.Context, notifications []Notification) error {
+	msg, err := e.message(notifications)
+	if err != nil {
+		return err
+	}
+
+	c, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if e.auth != nil {
+		if err := c.Auth(e.auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start mail data: %w", err)
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	if err := c.Quit(); err != nil {
+		return fmt.Errorf("failed to close SMTP session: %w", err)
+	}
+
+	return nil
+}
+
+func parseTemplate(name string, text string, def string) (*template.Template, error) {
+	if text == "" {
+		text = def
+	}
+
+	t, err := template.New(name).Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s template: %w", ErrInvalidConfigError, name, err)
+	}
+
+	return t, nil
+}
+
+func NewEmail(cfg conf.Email) (*Email, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("%w: email host, from and to are required", ErrInvalidConfigError)
+	}
+
+	switch cfg.TLS {
+	case "", "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("%w: unknown email tls mode %q", ErrInvalidConfigError, cfg.TLS)
+	}
+
+	// credentials are only sent over TLS
+	if cfg.TLS == "none" && cfg.Username != "" {
+		return nil, fmt.Errorf("%w: email username requires tls", ErrInvalidConfigError)
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = defaultSMTPPort
+		if cfg.TLS == "tls" {
+			port = implicitTLSPort
+		}
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultSendTimeout
+	}
+
+	subject, err := parseTemplate("subject", cfg.Subject, defaultSubject)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := parseTemplate("body", cfg.Body, defaultBody)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Email{
+		addr:    net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		host:    cfg.Host,
+		from:    cfg.From,
+		to:      cfg.To,
+		mode:    cfg.TLS,
+		tls:     &tls.Config{ServerName: cfg.Host, InsecureSkipVerify: cfg.InsecureSkipVerify},
+		subject: subject,
+		body:    body,
+		timeout: timeout,
+	}
+
+	if cfg.Username != "" {
+		e.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return e, nil
+}
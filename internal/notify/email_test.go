@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+// mail is a message received by smtpServer.
+type mail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a minimal SMTP server accepting a single session,
+// it offers STARTTLS and AUTH PLAIN if cert is set.
+type smtpServer struct {
+	ln    net.Listener
+	cert  *tls.Certificate
+	mails chan mail
+}
+
+func selfSigned(t *testing.T) *tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func newSMTPServer(t *testing.T, cert *tls.Certificate) *smtpServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpServer{ln: ln, cert: cert, mails: make(chan mail, 1)}
+	t.Cleanup(func() { ln.Close() })
+
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var m mail
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			switch {
+			case s.cert == nil:
+				_ = tp.PrintfLine("250 localhost")
+			case isTLS(conn):
+				_ = tp.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+			default:
+				_ = tp.PrintfLine("250-localhost\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready")
+
+			conn = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*s.cert}})
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			m.auth = string(raw)
+			_ = tp.PrintfLine("235 ok")
+		case "MAIL":
+			m.from = arg
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			m.to = append(m.to, arg)
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+
+			data, _ := tp.ReadDotBytes()
+			m.data = string(data)
+			_ = tp.PrintfLine("250 ok")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			s.mails <- m
+
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func isTLS(conn net.Conn) bool {
+	_, ok := conn.(*tls.Conn)
+
+	return ok
+}
+
+func newEmail(t *testing.T, cfg conf.Email) *Email {
+	t.Helper()
+
+	e, err := NewEmail(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestEmailPlain(t *testing.T) {
+	srv := newSMTPServer(t, nil)
+	resolved := firing()
+	resolved.State = "resolved"
+
+	e := newEmail(t, conf.Email{
+		Host: "localhost",
+		Port: srv.port(),
+		From: "minimon@example.test",
+		To:   []string{"ops@example.test", "dev@example.test"},
+		TLS:  "none",
+	})
+
+	if err := e.Send(context.Background(), []Notification{firing(), resolved}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := <-srv.mails
+
+	if m.from != "FROM:<minimon@example.test>" || len(m.to) != 2 || m.auth != "" {
+		t.Fatalf("got envelope %+v", m)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(m.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject := msg.Get("Subject"); subject != "[minimon] web1: 1 firing, 1 resolved" {
+		t.Fatalf("got subject %q", subject)
+	}
+
+	for _, want := range []string{"firing: load > 1", "resolved: load > 1", "value: 2"} {
+		if !strings.Contains(m.data, want) {
+			t.Fatalf("body %q does not contain %q", m.data, want)
+		}
+	}
+}
+
+func TestEmailStartTLSAuth(t *testing.T) {
+	srv := newSMTPServer(t, selfSigned(t))
+
+	e := newEmail(t, conf.Email{
+		Host:               "localhost",
+		Port:               srv.port(),
+		Username:           "minimon",
+		Password:           "secret",
+		From:               "minimon@example.test",
+		To:                 []string{"ops@example.test"},
+		TLS:                "starttls",
+		InsecureSkipVerify: true,
+	})
+
+	if err := e.Send(context.Background(), []Notification{firing()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := <-srv.mails; m.auth != "\x00minimon\x00secret" {
+		t.Fatalf("got auth %q", m.auth)
+	}
+}
+
+func TestEmailNoStartTLS(t *testing.T) {
+	srv := newSMTPServer(t, nil)
+
+	e := newEmail(t, conf.Email{
+		Host: "localhost",
+		Port: srv.port(),
+		From: "minimon@example.test",
+		To:   []string{"ops@example.test"},
+		TLS:  "starttls",
+	})
+
+	if err := e.Send(context.Background(), []Notification{firing()}); !errors.Is(err, ErrNoStartTLSError) {
+		t.Fatalf("got %v, want %v", err, ErrNoStartTLSError)
+	}
+}
+
+func TestNewEmailAuthWithoutTLS(t *testing.T) {
+	_, err := NewEmail(conf.Email{
+		Host:     "mail.example.test",
+		Port:     25,
+		Username: "minimon",
+		From:     "minimon@example.test",
+		To:       []string{"ops@example.test"},
+		TLS:      "none",
+	})
+	if !errors.Is(err, ErrInvalidConfigError) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidConfigError)
+	}
+}
+
+func TestNewEmailPort(t *testing.T) {
+	for mode, port := range map[string]int{"": defaultSMTPPort, "starttls": defaultSMTPPort, "tls": implicitTLSPort} {
+		e := newEmail(t, conf.Email{Host: "mail.example.test", From: "a@b", To: []string{"c@d"}, TLS: mode})
+
+		if want := "mail.example.test:" + strconv.Itoa(port); e.addr != want {
+			t.Fatalf("mode %q: got %s, want %s", mode, e.addr, want)
+		}
+	}
+}
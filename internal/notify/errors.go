@@ -0,0 +1,10 @@
+package notify
+
+import "errors"
+
+var (
+	ErrInvalidConfigError    = errors.New("invalid config")
+	ErrDuplicateChannelError = errors.New("duplicate notification channel")
+	ErrUnexpectedStatusError = errors.New("unexpected status")
+	ErrNoStartTLSError       = errors.New("server does not support STARTTLS")
+)
@@ -0,0 +1,296 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "log/slog"
+	"os"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/alert"
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
+	"github.com/kirill-shtrykov/minimon/internal/db/generated"
+)
+
+const (
+	defaultRetries = 10
+	defaultBackoff = 10 * time.Second
+	maxBackoff     = time.Hour
+	deliverBatch   = 100
+)
+
+// Notification is an alert transition as delivered to channels.
+type Notification struct {
+	Rule  string    `json:"rule"`
+	Key   string    `json:"key"`
+	Value float64   `json:"value"`
+	State string    `json:"state"`
+	From  string    `json:"from"`
+	Since time.Time `json:"since"`
+	At    time.Time `json:"at"`
+	Host  string    `json:"host"`
+}
+
+// Sender delivers notifications to a channel.
+type Sender interface {
+	Send(ctx context.Context, notifications []Notification) error
+}
+
+// channel is a named sender, a grouped channel gets all
+// notifications of one evaluation at once.
+type channel struct {
+	name    string
+	sender  Sender
+	grouped bool
+}
+
+// Dispatcher queues notifications in the outbox table and delivers
+// them to the channels, so they survive restarts and failed
+// deliveries are retried.
+type Dispatcher struct {
+	repo     *db.Repo
+	host     string
+	channels []channel
+	retries  int
+	backoff  time.Duration
+}
+
+func notifies(t alert.Transition) bool {
+	return t.To == alert.StateFiring || t.To == alert.StateResolved
+}
+
+// Notify queues firing and resolved transitions for every channel
+// in the transaction of tx.
+func (d *Dispatcher) Notify(ctx context.Context, tx *db.Repo, transitions []alert.Transition) error {
+	var notifications []Notification
+
+	for _, t := range transitions {
+		if !notifies(t) {
+			continue
+		}
+
+		notifications = append(notifications, Notification{
+			Rule:  t.Rule,
+			Key:   t.Key,
+			Value: t.Value,
+			State: string(t.To),
+			From:  string(t.From),
+			Since: t.Since,
+			At:    t.At,
+			Host:  d.host,
+		})
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	for _, c := range d.channels {
+		if c.grouped {
+			if err := enqueue(ctx, tx, c.name, notifications); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for _, n := range notifications {
+			if err := enqueue(ctx, tx, c.name, []Notification{n}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func enqueue(ctx context.Context, tx *db.Repo, name string, notifications []Notification) error {
+	payload, err := json.Marshal(notifications)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	err = tx.Enqueue(ctx, generated.EnqueueParams{
+		Channel:     name,
+		Payload:     payload,
+		NextAttempt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue notification: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) sender(name string) (Sender, bool) {
+	for _, c := range d.channels {
+		if c.name == name {
+			return c.sender, true
+		}
+	}
+
+	return nil, false
+}
+
+// retryIn returns the delay before the given attempt.
+func (d *Dispatcher) retryIn(attempt int64) time.Duration {
+	delay := d.backoff
+
+	for i := int64(1); i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
+
+// Deliver sends due notifications and returns how many were sent.
+// Failed ones are rescheduled with exponential backoff and dropped
+// when out of retries.
+func (d *Dispatcher) Deliver(ctx context.Context) (int, error) {
+	due, err := d.repo.DueNotifications(ctx, time.Now().UTC(), deliverBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deliver notifications: %w", err)
+	}
+
+	sent := 0
+
+	for _, e := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		sender, ok := d.sender(e.Channel)
+		if !ok {
+			log.WarnContext(ctx, "dropping notification of unknown channel", log.String("channel", e.Channel))
+
+			if err := d.repo.DeleteNotification(ctx, e.ID); err != nil {
+				return sent, fmt.Errorf("failed to deliver notifications: %w", err)
+			}
+
+			continue
+		}
+
+		var notifications []Notification
+
+		err := json.Unmarshal(e.Payload, &notifications)
+		if err == nil {
+			err = sender.Send(ctx, notifications)
+		}
+
+		if err == nil {
+			sent++
+			err = d.repo.DeleteNotification(ctx, e.ID)
+		} else {
+			err = d.retry(ctx, e, err)
+		}
+
+		if err != nil {
+			return sent, fmt.Errorf("failed to deliver notifications: %w", err)
+		}
+	}
+
+	return sent, nil
+}
+
+func (d *Dispatcher) retry(ctx context.Context, e generated.Outbox, sendErr error) error {
+	attempts := e.Attempts + 1
+
+	if attempts > int64(d.retries) {
+		log.ErrorContext(
+			ctx,
+			"dropping notification, out of retries",
+			log.String("channel", e.Channel),
+			log.Int64("attempts", attempts),
+			log.Any("error", sendErr),
+		)
+
+		return d.repo.DeleteNotification(ctx, e.ID)
+	}
+
+	log.WarnContext(
+		ctx,
+		"failed to send notification",
+		log.String("channel", e.Channel),
+		log.Int64("attempt", attempts),
+		log.Any("error", sendErr),
+	)
+
+	return d.repo.RetryNotification(ctx, generated.RetryNotificationParams{
+		Attempts:    attempts,
+		NextAttempt: time.Now().UTC().Add(d.retryIn(attempts)),
+		LastError:   sendErr.Error(),
+		ID:          e.ID,
+	})
+}
+
+func (d *Dispatcher) add(name string, sender Sender, grouped bool) error {
+	if _, ok := d.sender(name); ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateChannelError, name)
+	}
+
+	d.channels = append(d.channels, channel{name: name, sender: sender, grouped: grouped})
+
+	return nil
+}
+
+func New(repo *db.Repo, cfg conf.Notify) (*Dispatcher, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host name: %w", err)
+	}
+
+	d := &Dispatcher{
+		repo:    repo,
+		host:    host,
+		retries: defaultRetries,
+		backoff: time.Duration(cfg.Backoff),
+	}
+
+	if cfg.Retries != nil {
+		if *cfg.Retries < 0 {
+			return nil, fmt.Errorf("%w: negative retries", ErrInvalidConfigError)
+		}
+
+		d.retries = *cfg.Retries
+	}
+
+	if d.backoff <= 0 {
+		d.backoff = defaultBackoff
+	}
+
+	for _, w := range cfg.Webhooks {
+		sender, err := NewWebhook(w)
+		if err != nil {
+			return nil, err
+		}
+
+		name := w.Name
+		if name == "" {
+			name = w.URL
+		}
+
+		if err := d.add("webhook:"+name, sender, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range cfg.Emails {
+		sender, err := NewEmail(e)
+		if err != nil {
+			return nil, err
+		}
+
+		name := e.Name
+		if name == "" {
+			name = e.Host
+		}
+
+		if err := d.add("email:"+name, sender, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Register sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
+)
+
+// newTestDispatcher returns a dispatcher on an empty database with
+// a queued notification for a webhook failing with 500, and a
+// connection to inspect the outbox.
+func newTestDispatcher(t *testing.T, cfg conf.Notify) (*Dispatcher, *sql.DB) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "minimon.db")
+
+	schema, err := os.ReadFile("../db/queries/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	srv, _ := webhookServer(t, http.StatusInternalServerError)
+	cfg.Webhooks = []conf.Webhook{{Name: "ops", URL: srv.URL}}
+
+	payload, err := json.Marshal([]Notification{firing()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Exec(`INSERT INTO outbox (channel, payload, next_attempt)
+		VALUES ('webhook:ops', ?, '2026-01-01 00:00:00')`, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := db.New(conf.SQLiteConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New(repo, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d, conn
+}
+
+func TestDeliverRetries(t *testing.T) {
+	retries := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		retries  *int
+		want     int
+		attempts int
+	}{
+		{name: "default", want: defaultRetries, attempts: 1},
+		{name: "once", retries: retries(1), want: 1, attempts: 1},
+		{name: "none", retries: retries(0), want: 0, attempts: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, conn := newTestDispatcher(t, conf.Notify{Retries: tt.retries})
+			if d.retries != tt.want {
+				t.Fatalf("got %d retries, want %d", d.retries, tt.want)
+			}
+
+			if _, err := d.Deliver(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			attempts := -1
+
+			err := conn.QueryRow(`SELECT attempts FROM outbox`).Scan(&attempts)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				t.Fatal(err)
+			}
+
+			if attempts != tt.attempts {
+				t.Fatalf("got %d attempts queued, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+
+	if _, err := New(nil, conf.Notify{Retries: retries(-1)}); !errors.Is(err, ErrInvalidConfigError) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidConfigError)
+	}
+}
@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+const defaultSendTimeout = 10 * time.Second
+
+// Webhook posts every notification as its own request, the body
+// is the JSON notification or the rendered template.
+type Webhook struct {
+	client   *http.Client
+	url      string
+	headers  map[string]string
+	template *template.Template
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func (w *Webhook) body(n Notification) ([]byte, error) {
+	if w.template == nil {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal notification: %w", err)
+		}
+
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, n); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// contentType returns the type of a rendered body, a template
+// may render JSON or plain text.
+func contentType(body []byte) string {
+	if json.Valid(body) {
+		return "application/json"
+	}
+
+	return "text/plain; charset=utf-8"
+}
+
+func (w *Webhook) post(ctx context.Context, n Notification) error {
+	body, err := w.body(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType(body))
+
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatusError, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (w *Webhook) Send(ctx context.Context, notifications []Notification) error {
+	for _, n := range notifications {
+		if err := w.post(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func NewWebhook(cfg conf.Webhook) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: webhook url is required", ErrInvalidConfigError)
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultSendTimeout
+	}
+
+	w := &Webhook{
+		client:  &http.Client{Timeout: timeout},
+		url:     cfg.URL,
+		headers: cfg.Headers,
+	}
+
+	if cfg.Template != "" {
+		t, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("%w: webhook template: %w", ErrInvalidConfigError, err)
+		}
+
+		w.template = t
+	}
+
+	return w, nil
+}
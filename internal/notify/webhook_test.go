@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+)
+
+type request struct {
+	contentType string
+	auth        string
+	body        string
+}
+
+// webhookServer records requests and answers them with status.
+func webhookServer(t *testing.T, status int) (*httptest.Server, <-chan request) {
+	t.Helper()
+
+	requests := make(chan request, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{
+			contentType: r.Header.Get("Content-Type"),
+			auth:        r.Header.Get("Authorization"),
+			body:        string(body),
+		}
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, requests
+}
+
+func newWebhook(t *testing.T, cfg conf.Webhook) *Webhook {
+	t.Helper()
+
+	w, err := NewWebhook(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w
+}
+
+func firing() Notification {
+	return Notification{
+		Rule:  "load > 1",
+		Key:   "load",
+		Value: 2,
+		State: "firing",
+		From:  "inactive",
+		At:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Host:  "web1",
+	}
+}
+
+func TestWebhookJSON(t *testing.T) {
+	srv, requests := webhookServer(t, http.StatusOK)
+	w := newWebhook(t, conf.Webhook{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer x"}})
+
+	if err := w.Send(context.Background(), []Notification{firing(), firing()}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		r := <-requests
+
+		var n Notification
+		if err := json.Unmarshal([]byte(r.body), &n); err != nil {
+			t.Fatal(err)
+		}
+
+		if n != firing() || r.contentType != "application/json" || r.auth != "Bearer x" {
+			t.Fatalf("got %+v with %q, %q", n, r.contentType, r.auth)
+		}
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		headers     map[string]string
+		body        string
+		contentType string
+	}{
+		{
+			name:        "text",
+			template:    "{{.Rule}} is {{.State}} on {{.Host}}",
+			body:        "load > 1 is firing on web1",
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:        "json",
+			template:    `{"text": {{json .Key}}}`,
+			body:        `{"text": "load"}`,
+			contentType: "application/json",
+		},
+		{
+			name:        "header",
+			template:    "{{.Key}}",
+			headers:     map[string]string{"Content-Type": "text/markdown"},
+			body:        "load",
+			contentType: "text/markdown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := webhookServer(t, http.StatusNoContent)
+			w := newWebhook(t, conf.Webhook{URL: srv.URL, Template: tt.template, Headers: tt.headers})
+
+			if err := w.Send(context.Background(), []Notification{firing()}); err != nil {
+				t.Fatal(err)
+			}
+
+			if r := <-requests; r.body != tt.body || r.contentType != tt.contentType {
+				t.Fatalf("got %q as %q, want %q as %q", r.body, r.contentType, tt.body, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	srv, _ := webhookServer(t, http.StatusBadGateway)
+	w := newWebhook(t, conf.Webhook{URL: srv.URL})
+
+	if err := w.Send(context.Background(), []Notification{firing()}); err == nil {
+		t.Fatal("got no error for 502")
+	}
+}
@@ -0,0 +1,64 @@
+// Package collector defines the interface metric collectors
+// implement and the registry they are created from.
+package collector
+
+import "context"
+
+// Sample is a collected value. A non-empty Name is appended to the
+// metric key on store. Type is "int", "float" or "string" and
+// defaults to the configured metric type. Value is an int, float64
+// or string matching the type. Labels tell apart samples of the same
+// name, like the mountpoint of a disk.
+type Sample struct {
+	Name   string            `json:"name,omitempty"`
+	Type   string            `json:"type,omitempty"`
+	Value  any               `json:"value"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func Int(name string, v int) Sample {
+	return Sample{Name: name, Type: "int", Value: v}
+}
+
+func Float(name string, v float64) Sample {
+	return Sample{Name: name, Type: "float", Value: v}
+}
+
+func String(name string, v string) Sample {
+	return Sample{Name: name, Type: "string", Value: v}
+}
+
+// Desc describes what a collector collects, Names are
+// the names of its samples when known up front. Samples are
+// stored under Key with Labels added to their own, Unit is
+// the unit of their values.
+type Desc struct {
+	Key    string            `json:"key,omitempty"`
+	Help   string            `json:"help,omitempty"`
+	Names  []string          `json:"names,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Unit   string            `json:"unit,omitempty"`
+}
+
+// Collector collects the samples of one configured metric.
+// Collect is called from one goroutine at a time and must
+// return when the context is done.
+type Collector interface {
+	Describe() Desc
+	Collect(ctx context.Context) ([]Sample, error)
+}
+
+// Config is the configuration of a metric a collector is created
+// for. Decode decodes the configuration section named after the
+// collection method into v, v is left unchanged without one.
+// Root overrides the procfs or sysfs mount point.
+type Config struct {
+	Key    string
+	Method string
+	Type   string
+	Root   string
+	Decode func(v any) error
+}
+
+// Factory creates the collector of a configured metric.
+type Factory func(cfg Config) (Collector, error)
@@ -0,0 +1,9 @@
+package collector
+
+import "errors"
+
+var (
+	ErrUnknownMethodError = errors.New("unknown collection method")
+	ErrUnknownKeyError    = errors.New("unknown key")
+	ErrDuplicateError     = errors.New("already registered")
+)
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Registry maps collection methods to collector factories, and for
+// methods collecting several key families, key prefixes (the part
+// before the first dot) to factories.
+type Registry struct {
+	mu       sync.RWMutex
+	methods  map[string]Factory
+	families map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{methods: make(map[string]Factory), families: make(map[string]Factory)}
+}
+
+func (r *Registry) Register(method string, f Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.methods[method]; ok {
+		return fmt.Errorf("method %s: %w", method, ErrDuplicateError)
+	}
+
+	r.methods[method] = f
+
+	return nil
+}
+
+// RegisterFamily registers the factory of keys starting with
+// `<prefix>.`, created by NewFamily.
+func (r *Registry) RegisterFamily(prefix string, f Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.families[prefix]; ok {
+		return fmt.Errorf("key family %s: %w", prefix, ErrDuplicateError)
+	}
+
+	r.families[prefix] = f
+
+	return nil
+}
+
+// New creates the collector of the metric by its method.
+func (r *Registry) New(cfg Config) (Collector, error) {
+	r.mu.RLock()
+	f, ok := r.methods[cfg.Method]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMethodError, cfg.Method)
+	}
+
+	return f(cfg)
+}
+
+// NewFamily creates the collector of the metric by its key family,
+// it is the factory of methods made of key families.
+func (r *Registry) NewFamily(cfg Config) (Collector, error) {
+	prefix, _, _ := strings.Cut(cfg.Key, ".")
+
+	r.mu.RLock()
+	f, ok := r.families[prefix]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyError, cfg.Key)
+	}
+
+	return f(cfg)
+}
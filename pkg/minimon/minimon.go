@@ -0,0 +1,165 @@
+// Package minimon runs the monitor. Builds with in-house collectors
+// register them in a collector.Registry and pass it to Run.
+package minimon
+
+import (
+	"context"
+	log "log/slog"
+	"os"
+	"os/signal"
+
+	// Register sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kirill-shtrykov/minimon/internal/alert"
+	"github.com/kirill-shtrykov/minimon/internal/app"
+	"github.com/kirill-shtrykov/minimon/internal/conf"
+	"github.com/kirill-shtrykov/minimon/internal/db"
+	"github.com/kirill-shtrykov/minimon/internal/monitor"
+	"github.com/kirill-shtrykov/minimon/internal/notify"
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+	"github.com/kirill-shtrykov/minimon/pkg/flags"
+)
+
+func setupLogging(ctx context.Context, debug bool) {
+	log.InfoContext(ctx, "MiniMon - lightweight monitoring utility")
+
+	if debug {
+		log.SetLogLoggerLevel(log.LevelDebug)
+		log.DebugContext(ctx, "debug mode on")
+	}
+}
+
+// Run runs minimon with the collectors of the registry and the
+// built-in ones, it returns the process exit code.
+func Run(reg *collector.Registry) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	f := flags.Parse()
+	setupLogging(ctx, f.Debug)
+
+	cfg, err := conf.LoadConfig(f.Conf)
+	if err != nil {
+		log.ErrorContext(ctx, "Failed to read config", log.Any("error", err))
+
+		return 1
+	}
+
+	const chans = 5
+
+	errCh := make(chan error, chans)
+
+	r, err := db.New(cfg.DB)
+	if err != nil {
+		log.ErrorContext(ctx, "database connection failed", log.Any("error", err))
+
+		return 1
+	}
+
+	migrated, err := r.Migrate(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to migrate database", log.Any("error", err))
+
+		return 1
+	}
+
+	if migrated {
+		log.InfoContext(ctx, "migrated database to series")
+	}
+
+	if err := monitor.Register(reg); err != nil {
+		log.ErrorContext(ctx, "failed to register collectors", log.Any("error", err))
+
+		return 1
+	}
+
+	svc, err := monitor.New(r, reg, cfg.Metrics)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create service", log.Any("error", err))
+
+		return 1
+	}
+
+	if err := svc.MigrateKeys(ctx); err != nil {
+		log.ErrorContext(ctx, "failed to migrate keys", log.Any("error", err))
+
+		return 1
+	}
+
+	pruner, err := app.NewPruner(cfg.Retention, svc)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create pruner", log.Any("error", err))
+
+		return 1
+	}
+
+	alerts, err := alert.New(ctx, r, cfg.Alerts)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create alerts", log.Any("error", err))
+
+		return 1
+	}
+
+	dispatcher, err := notify.New(r, cfg.Notify)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create notifications", log.Any("error", err))
+
+		return 1
+	}
+
+	if cfg.Rollup.Enabled {
+		svc.EnableRollups()
+	}
+
+	srv := app.NewHTTPServer(svc, cfg.Dashboard)
+
+	go func() {
+		if err := srv.Run(ctx, f.Addr); err != nil {
+			errCh <- err
+		}
+	}()
+
+	mon := app.NewMonitor(cfg, svc, alerts, dispatcher)
+
+	go func() {
+		if err := mon.Run(ctx); err != nil {
+			errCh <- err
+		}
+	}()
+
+	go func() {
+		if err := pruner.Run(ctx); err != nil {
+			errCh <- err
+		}
+	}()
+
+	notifier := app.NewNotifier(cfg.Notify, dispatcher)
+
+	go func() {
+		if err := notifier.Run(ctx); err != nil {
+			errCh <- err
+		}
+	}()
+
+	if cfg.Rollup.Enabled {
+		roller := app.NewRoller(cfg.Rollup, svc)
+
+		go func() {
+			if err := roller.Run(ctx); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		log.InfoContext(ctx, "Shutting down MiniMon")
+	case err := <-errCh:
+		log.ErrorContext(ctx, "Application shutdown unexpectedly", log.Any("error", err))
+
+		return 1
+	}
+
+	return 0
+}
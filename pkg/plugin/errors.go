@@ -0,0 +1,8 @@
+package plugin
+
+import "errors"
+
+var (
+	ErrProtocolError        = errors.New("plugin protocol error")
+	ErrVersionMismatchError = errors.New("unsupported protocol version")
+)
@@ -0,0 +1,42 @@
+// Package plugin implements the protocol of long running collector
+// plugins. Minimon starts the plugin and exchanges JSON messages,
+// one per line, over its standard input and output:
+//
+//	-> {"type":"handshake","version":1,"key":"gpu","config":{...}}
+//	<- {"type":"describe","version":1,"desc":{"help":"...","names":["temp"]}}
+//	-> {"type":"collect","id":1}
+//	<- {"type":"samples","id":1,"samples":[{"name":"temp","type":"float","value":42,"labels":{"gpu":"0"}}]}
+//
+// Labels of the description are added to the labels of every sample.
+// A failed collection is answered with {"type":"error","id":1,"error":"..."}.
+// The plugin exits when its standard input is closed. Standard error
+// is passed through to the minimon log.
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+)
+
+const Version = 1
+
+const (
+	TypeHandshake = "handshake"
+	TypeDescribe  = "describe"
+	TypeCollect   = "collect"
+	TypeSamples   = "samples"
+	TypeError     = "error"
+)
+
+// Message is a protocol message, fields are set depending on Type.
+type Message struct {
+	Type    string             `json:"type"`
+	Version int                `json:"version,omitempty"`
+	ID      uint64             `json:"id,omitempty"`
+	Key     string             `json:"key,omitempty"`
+	Config  json.RawMessage    `json:"config,omitempty"`
+	Desc    *collector.Desc    `json:"desc,omitempty"`
+	Samples []collector.Sample `json:"samples,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/kirill-shtrykov/minimon/pkg/collector"
+)
+
+const maxMessageSize = 1 << 20
+
+// Plugin is a collector served over the plugin protocol.
+type Plugin interface {
+	// Init sets the plugin up for the metric key with the options
+	// of the metric configuration and describes what it collects.
+	Init(key string, config json.RawMessage) (collector.Desc, error)
+	Collect(ctx context.Context) ([]collector.Sample, error)
+}
+
+func reply(enc *json.Encoder, m Message) error {
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	return nil
+}
+
+func handle(ctx context.Context, p Plugin, m Message, ready bool) (Message, error) {
+	switch m.Type {
+	case TypeHandshake:
+		if m.Version != Version {
+			err := fmt.Errorf("%w: %d", ErrVersionMismatchError, m.Version)
+
+			return Message{Type: TypeError, Error: err.Error()}, err
+		}
+
+		desc, err := p.Init(m.Key, m.Config)
+		if err != nil {
+			return Message{Type: TypeError, Error: err.Error()}, fmt.Errorf("failed to init plugin: %w", err)
+		}
+
+		return Message{Type: TypeDescribe, Version: Version, Desc: &desc}, nil
+	case TypeCollect:
+		if !ready {
+			return Message{Type: TypeError, ID: m.ID, Error: "collect before handshake"}, nil
+		}
+
+		samples, err := p.Collect(ctx)
+		if err != nil {
+			return Message{Type: TypeError, ID: m.ID, Error: err.Error()}, nil
+		}
+
+		return Message{Type: TypeSamples, ID: m.ID, Samples: samples}, nil
+	}
+
+	return Message{Type: TypeError, ID: m.ID, Error: "unknown message type " + m.Type}, nil
+}
+
+// Serve answers the messages read from r on w until r ends. It
+// returns an error when the handshake fails or r is malformed.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, p Plugin) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxMessageSize)
+
+	enc := json.NewEncoder(w)
+	ready := false
+
+	for scanner.Scan() {
+		var m Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return fmt.Errorf("%w: %w", ErrProtocolError, err)
+		}
+
+		resp, err := handle(ctx, p, m, ready)
+		if werr := reply(enc, resp); werr != nil {
+			return werr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if m.Type == TypeHandshake {
+			ready = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read message: %w", err)
+	}
+
+	return nil
+}
+
+// Main serves the plugin on the standard input and output and exits,
+// it is meant to be called from the main function of a plugin.
+func Main(p Plugin) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	err := Serve(ctx, os.Stdin, os.Stdout, p)
+
+	stop()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}